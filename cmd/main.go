@@ -1,71 +1,349 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"k8s-resource-adjustment/internal/commitmsg"
 	"k8s-resource-adjustment/internal/config"
 	"k8s-resource-adjustment/internal/gitops"
+	"k8s-resource-adjustment/internal/guardrails"
 	"k8s-resource-adjustment/internal/k8s"
+	"k8s-resource-adjustment/internal/pool"
+	"k8s-resource-adjustment/internal/provider"
+	"k8s-resource-adjustment/internal/report"
+)
 
-	"k8s.io/apimachinery/pkg/api/resource"
+// Exit codes of the application.
+const (
+	exitOK = 0
+	// exitFailed means some repositories failed or violated a guardrail.
+	exitFailed = 1
+	// exitInvalidConfig means the configuration is invalid and no repository
+	// was processed.
+	exitInvalidConfig = 2
+	// exitAborted means the run was interrupted, timed out or stopped by
+	// FAILURE_THRESHOLD before every repository was processed.
+	exitAborted = 3
 )
 
 func main() {
-	var (
-		configLoader config.ConfigLoader   = &config.EnvConfigLoader{}
-		gitManager   gitops.GitRepoManager = &gitops.InMemoryGitRepoManager{}
-		patcher      k8s.ResourcePatcher   = &k8s.DefaultResourcePatcher{}
-	)
-
-	cfg := configLoader.Load()
-	for _, url := range cfg.RepoURLs {
-		fmt.Println("======== Processing Repository:", url, "========")
-		repoURL := fmt.Sprintf("%s/%s", cfg.BaseURL, url)
-		worktree, repo, err := gitManager.CloneAndWorktree(repoURL, cfg.Branch)
-		if err != nil {
-			fmt.Printf("Failed to clone repo: %v\n", err)
-			continue
+	configLoader := config.NewConfigLoader()
+
+	// On the first interrupt no further repositories are started and those in
+	// progress finish their push; a second interrupt exits immediately.
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+
+	// Problems found while loading the configuration, reading the signing
+	// key and the commit message template are reported together.
+	cfg, err := configLoader.Load(signalCtx)
+	problems := []error{err}
+	ctx := signalCtx
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	gitToken := cfg.GitToken
+	if gitToken == "" && cfg.GitUsername == "" && cfg.GitPassword == "" {
+		gitToken = cfg.GitLabToken
+		if cfg.Provider == "github" {
+			gitToken = cfg.GitHubToken
 		}
+	}
+	author := gitops.Identity{Name: cfg.AuthorName, Email: cfg.AuthorEmail}
+	if author == (gitops.Identity{}) {
+		author = gitops.DefaultIdentity
+	}
+	repoManager := &gitops.InMemoryGitRepoManager{
+		Author:    author,
+		Committer: gitops.Identity{Name: cfg.CommitterName, Email: cfg.CommitterEmail},
+		Auth: &gitops.Auth{
+			Token:            gitToken,
+			Username:         cfg.GitUsername,
+			Password:         cfg.GitPassword,
+			NetrcPath:        cfg.NetrcPath,
+			SSHKeyPath:       cfg.SSHKeyPath,
+			SSHKeyPassphrase: cfg.SSHKeyPassphrase,
+			KnownHostsPath:   cfg.SSHKnownHosts,
+		},
+	}
+	if err := configureSigning(repoManager, cfg); err != nil {
+		problems = append(problems, err)
+	}
+	commitTemplate, err := loadCommitTemplate(cfg)
+	problems = append(problems, err)
+	if err := errors.Join(problems...); err != nil {
+		invalidConfig("Invalid configuration:\n%v", err)
+	}
+	var gitManager gitops.GitRepoManager = repoManager
 
-		targetPath := filepath.Join("overlays", cfg.Env, "patches", "set_resources.yaml")
-		file, err := gitManager.GetFile(worktree, targetPath)
-		if err != nil {
-			fmt.Printf("Failed to read file: %v\n", err)
-			continue
+	nodePatcher := &k8s.NodeResourcePatcher{CustomKinds: cfg.Parsed.CustomKinds}
+	var patcher k8s.ResourcePatcher = nodePatcher
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Repositories)) {
+		if !slices.Contains(cfg.RepoURLs, name) {
+			log.Printf("Warning: repository %s is configured but not in REPO_URLS, ignoring it", name)
 		}
+	}
 
-		manifest, err := patcher.Patch(file, k8s.ResourceConfig{
-			CPURequest: resource.MustParse(cfg.CPURequest),
-			MemRequest: resource.MustParse(cfg.MemRequest),
-			CPULimit:   resource.MustParse(cfg.CPULimit),
-			MemLimit:   resource.MustParse(cfg.MemLimit),
-		})
+	var validator guardrails.Validator = &guardrails.ResourceValidator{
+		Lister:               nodePatcher,
+		Min:                  cfg.Parsed.MinResources,
+		Max:                  cfg.Parsed.MaxResources,
+		MaxLimitRequestRatio: cfg.Parsed.MaxLimitRequestRatio,
+	}
+
+	var trailers []string
+	if cfg.SignOff {
+		trailers = append(trailers, fmt.Sprintf("Signed-off-by: %s <%s>", author.Name, author.Email))
+	}
+	trailers = append(trailers, cfg.CommitTrailers...)
+	runID := cfg.RunID
+	if runID == "" {
+		runID = time.Now().Format("20060102-150405")
+	}
+
+	var opener provider.ChangeRequestOpener
+	mrBranch := fmt.Sprintf("%s%s-%s", cfg.MRBranchPrefix, cfg.Env, runID)
+	mrTarget := cfg.MRTargetBranch
+	if mrTarget == "" {
+		mrTarget = strings.TrimPrefix(cfg.Branch, "refs/heads/")
+	}
+	if cfg.MergeRequest {
+		baseURL, token := cfg.GitLabBaseURL, cfg.GitLabToken
+		if cfg.Provider == "github" {
+			baseURL, token = cfg.GitHubBaseURL, cfg.GitHubToken
+		}
+		opener, err = provider.New(cfg.Provider, baseURL, token)
 		if err != nil {
-			fmt.Printf("Failed to update resource: %v\n", err)
-			continue
+			invalidConfig("Failed to set up merge requests: %v", err)
+		}
+	}
+
+	maxFailures := cfg.Parsed.FailureLimit
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
+	proc := &processor{
+		cfg:            cfg,
+		gitManager:     gitManager,
+		patcher:        patcher,
+		lister:         nodePatcher,
+		validator:      validator,
+		opener:         opener,
+		resCfg:         cfg.Parsed.Resources,
+		repoResCfgs:    cfg.Parsed.RepositoryResources,
+		commitTemplate: commitTemplate,
+		trailers:       trailers,
+		runID:          runID,
+		mrBranch:       mrBranch,
+		mrTarget:       mrTarget,
+	}
+	started := time.Now()
+	failures := 0
+	results := pool.Run(cfg.Workers, cfg.RepoURLs, func(url string) result {
+		start := time.Now()
+		var out bytes.Buffer
+		fmt.Fprintln(&out, "======== Processing Repository:", url, "========")
+		r := result{url: url}
+		r.outcome = proc.process(ctx, &r, &out)
+		r.output = out.String()
+		r.duration = time.Since(start)
+		return r
+	}, func(_ int, r result) {
+		// Print the output of each repository at once so that the output of
+		// concurrent workers is not interleaved.
+		fmt.Print(r.output)
+		if r.outcome.failed() {
+			failures++
+			if maxFailures >= 0 && failures > maxFailures {
+				abort(fmt.Errorf("%d repositories failed, more than FAILURE_THRESHOLD %s", failures, cfg.FailureThreshold))
+			}
 		}
+	})
+	fmt.Println("======== Finished Processing Repository ========")
+	code := exitCode(results)
+	if code == exitAborted {
+		fmt.Println("Run stopped early:", context.Cause(ctx))
+	}
+	printSummary(results)
+	if cfg.DryRun {
+		changed := 0
+		for _, r := range results {
+			if r.outcome == outcomeWouldChange {
+				changed++
+			}
+		}
+		fmt.Printf("Dry run: %d of %d repositories would change\n", changed, len(cfg.RepoURLs))
+	}
 
-		f, err := worktree.Filesystem.Create(targetPath)
+	rep := &report.Report{
+		RunID:    runID,
+		Env:      cfg.Env,
+		DryRun:   cfg.DryRun,
+		Started:  started,
+		Duration: time.Since(started),
+	}
+	for _, r := range results {
+		rep.Repositories = append(rep.Repositories, r.repository())
+	}
+	writeReport(cfg.ReportJSONFile, rep.WriteJSON)
+	writeReport(cfg.ReportMarkdownFile, rep.WriteMarkdown)
+	writeReport(cfg.ReportJUnitFile, rep.WriteJUnit)
+
+	os.Exit(code)
+}
+
+// invalidConfig logs the configuration error and exits with
+// exitInvalidConfig.
+func invalidConfig(format string, args ...any) {
+	log.Printf(format, args...)
+	os.Exit(exitInvalidConfig)
+}
+
+// exitCode returns the exit code of a run with results. The run only counts
+// as aborted when it stopped before a repository was done.
+func exitCode(results []result) int {
+	code := exitOK
+	for _, r := range results {
+		switch {
+		case r.outcome == outcomeCanceled:
+			return exitAborted
+		case r.outcome.failed():
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+// writeReport writes the run report to path with write, unless path is
+// empty.
+func writeReport(path string, write func(io.Writer) error) {
+	if path == "" {
+		return
+	}
+	f, err := os.Create(path)
+	if err == nil {
+		err = write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to write report %s: %v", path, err)
+		return
+	}
+	fmt.Println("Report written to", path)
+}
+
+// upToDate reports whether manifest holds the same resources as file, with
+// quantities compared semantically. Manifests whose resources cannot be
+// listed are considered changed.
+func upToDate(lister guardrails.ResourceLister, file, manifest []byte) bool {
+	if bytes.Equal(file, manifest) {
+		return true
+	}
+	before, err := lister.Resources(file)
+	if err != nil {
+		return false
+	}
+	after, err := lister.Resources(manifest)
+	if err != nil {
+		return false
+	}
+	return k8s.EqualResources(before, after)
+}
+
+// configureSigning sets the key commits are signed with, if one is
+// configured.
+func configureSigning(manager *gitops.InMemoryGitRepoManager, cfg config.Config) error {
+	key := []byte(cfg.SigningKey)
+	if cfg.SigningKeyFile != "" {
+		data, err := os.ReadFile(cfg.SigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("SIGNING_KEY_FILE: %w", err)
+		}
+		key = data
+	}
+	if len(key) == 0 {
+		return nil
+	}
+	// Other formats are reported by Config.Validate.
+	switch cfg.SigningFormat {
+	case "openpgp":
+		entity, err := gitops.LoadOpenPGPKey(key, cfg.SigningKeyPassphrase)
 		if err != nil {
-			fmt.Printf("Failed to open file for writing: %v\n", err)
-			continue
+			return fmt.Errorf("SIGNING_KEY: %w", err)
 		}
-		_, err = f.Write(manifest)
+		manager.SignKey = entity
+	case "ssh":
+		signer, err := gitops.NewSSHSigner(key, cfg.SigningKeyPassphrase)
 		if err != nil {
-			fmt.Printf("Failed to write updated YAML: %v\n", err)
-			f.Close()
-			continue
+			return fmt.Errorf("SIGNING_KEY: %w", err)
 		}
-		f.Close()
+		manager.Signer = signer
+	}
+	return nil
+}
 
-		err = gitManager.CommitAndPush(repo, worktree, targetPath)
+// loadCommitTemplate returns the commit message template of cfg, read from
+// COMMIT_MESSAGE_TEMPLATE_FILE if it is set.
+func loadCommitTemplate(cfg config.Config) (*commitmsg.Template, error) {
+	text := cfg.CommitMessageTemplate
+	if cfg.CommitMessageTemplateFile != "" {
+		data, err := os.ReadFile(cfg.CommitMessageTemplateFile)
 		if err != nil {
-			fmt.Printf("Failed to commit/push: %v\n", err)
-			continue
+			return nil, fmt.Errorf("COMMIT_MESSAGE_TEMPLATE_FILE: %w", err)
 		}
+		text = string(data)
+	}
+	tmpl, err := commitmsg.New(text)
+	if err != nil {
+		return nil, fmt.Errorf("COMMIT_MESSAGE_TEMPLATE: %w", err)
+	}
+	return tmpl, nil
+}
 
-		fmt.Printf("Updated file content and pushed to remote!!!\n")
+// changes returns the changed resource values of the containers of file,
+// or nil if the resources cannot be listed.
+func changes(lister guardrails.ResourceLister, file, manifest []byte) []commitmsg.Change {
+	before, err := lister.Resources(file)
+	if err != nil {
+		return nil
 	}
-	fmt.Println("======== Finished Processing Repository ========")
+	after, err := lister.Resources(manifest)
+	if err != nil {
+		return nil
+	}
+	return commitmsg.Changes(before, after)
+}
+
+// mergeRequestDescription appends the diff of the change to description.
+func mergeRequestDescription(description, unified string) string {
+	var sb strings.Builder
+	if description != "" {
+		sb.WriteString(description)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("```diff\n")
+	sb.WriteString(unified)
+	sb.WriteString("```\n")
+	return sb.String()
 }
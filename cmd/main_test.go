@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []outcome
+		want     int
+	}{
+		{"empty run", nil, exitOK},
+		{"all succeeded", []outcome{outcomeUpdated, outcomeUnchanged, outcomeWouldChange}, exitOK},
+		{"one failed", []outcome{outcomeUpdated, outcomeFailed}, exitFailed},
+		{"guardrail violation", []outcome{outcomeUpdated, outcomeSkipped}, exitFailed},
+		{"threshold reached on the last repository", []outcome{outcomeFailed, outcomeFailed}, exitFailed},
+		{"canceled", []outcome{outcomeFailed, outcomeUpdated, outcomeCanceled}, exitAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []result
+			for _, o := range tt.outcomes {
+				results = append(results, result{outcome: o})
+			}
+			assert.Equal(t, tt.want, exitCode(results))
+		})
+	}
+}
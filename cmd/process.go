@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s-resource-adjustment/internal/commitmsg"
+	"k8s-resource-adjustment/internal/config"
+	"k8s-resource-adjustment/internal/diff"
+	"k8s-resource-adjustment/internal/gitops"
+	"k8s-resource-adjustment/internal/guardrails"
+	"k8s-resource-adjustment/internal/k8s"
+	"k8s-resource-adjustment/internal/provider"
+	"k8s-resource-adjustment/internal/report"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// outcome is the result of processing a repository.
+type outcome int
+
+const (
+	// outcomeUpdated means the change was pushed, and its merge request
+	// opened if requested.
+	outcomeUpdated outcome = iota
+	outcomeUnchanged
+	// outcomeWouldChange means a dry run found a change.
+	outcomeWouldChange
+	// outcomeSkipped means the change violated a guardrail.
+	outcomeSkipped
+	outcomeFailed
+	// outcomeCanceled means the run was interrupted before the repository
+	// was done.
+	outcomeCanceled
+)
+
+func (o outcome) String() string {
+	switch o {
+	case outcomeUpdated:
+		return "updated"
+	case outcomeUnchanged:
+		return "unchanged"
+	case outcomeWouldChange:
+		return "would change"
+	case outcomeSkipped:
+		return "skipped"
+	case outcomeCanceled:
+		return "canceled"
+	default:
+		return "failed"
+	}
+}
+
+// failed reports whether o counts as a failure for the exit code and
+// FAILURE_THRESHOLD. Guardrail violations count, since nothing was pushed.
+func (o outcome) failed() bool {
+	return o == outcomeFailed || o == outcomeSkipped
+}
+
+// status returns the report status of o.
+func (o outcome) status() report.Status {
+	switch o {
+	case outcomeUpdated:
+		return report.StatusUpdated
+	case outcomeUnchanged:
+		return report.StatusUnchanged
+	case outcomeWouldChange:
+		return report.StatusWouldChange
+	case outcomeSkipped:
+		return report.StatusSkipped
+	case outcomeCanceled:
+		return report.StatusCanceled
+	default:
+		return report.StatusFailed
+	}
+}
+
+// result is the outcome and output of processing a repository.
+type result struct {
+	url     string
+	outcome outcome
+	output  string
+	// category and err describe why the repository was skipped, failed or
+	// was canceled.
+	category     report.Category
+	err          error
+	commit       string
+	mergeRequest string
+	changes      []commitmsg.Change
+	duration     time.Duration
+}
+
+// fail writes the failure to action to out, records it in r with its
+// category and returns outcomeFailed.
+func (r *result) fail(out io.Writer, category report.Category, action string, err error) outcome {
+	fmt.Fprintf(out, "Failed to %s: %v\n", action, err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		category = report.CategoryTimeout
+	}
+	r.category, r.err = category, err
+	return outcomeFailed
+}
+
+// repository returns r for the run report.
+func (r *result) repository() report.Repository {
+	repo := report.Repository{
+		Repository:   r.url,
+		Status:       r.outcome.status(),
+		Category:     r.category,
+		Commit:       r.commit,
+		MergeRequest: r.mergeRequest,
+		Changes:      r.changes,
+		Duration:     r.duration,
+		Output:       r.output,
+	}
+	if r.err != nil {
+		repo.Error = r.err.Error()
+	}
+	return repo
+}
+
+// processor patches, commits and pushes a single repository. It is shared
+// by the workers and must not be modified while they run.
+type processor struct {
+	cfg        config.Config
+	gitManager gitops.GitRepoManager
+	patcher    k8s.ResourcePatcher
+	lister     guardrails.ResourceLister
+	validator  guardrails.Validator
+	opener     provider.ChangeRequestOpener
+	resCfg     k8s.ResourceConfig
+	// repoResCfgs replaces resCfg for the repositories with their own
+	// resource values.
+	repoResCfgs    map[string]k8s.ResourceConfig
+	commitTemplate *commitmsg.Template
+	trailers       []string
+	runID          string
+	mrBranch       string
+	mrTarget       string
+}
+
+// process processes the repository r.url, records the details in r and
+// writes its progress to out. Once the run is canceled, repositories that
+// have not started are not processed, and failures caused by the
+// cancellation count as canceled.
+func (p *processor) process(ctx context.Context, r *result, out io.Writer) outcome {
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(out, "Not processed: %v\n", err)
+		r.category, r.err = report.CategoryCanceled, err
+		return outcomeCanceled
+	}
+	if p.cfg.RepoTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.RepoTimeout)
+		defer cancel()
+	}
+
+	o := p.processWithRetries(ctx, r, out)
+	if o == outcomeFailed && errors.Is(ctx.Err(), context.Canceled) {
+		r.category = report.CategoryCanceled
+		return outcomeCanceled
+	}
+	return o
+}
+
+// processWithRetries clones the repository r.url and applies the change,
+// fetching and applying it again while the push is rejected.
+func (p *processor) processWithRetries(ctx context.Context, r *result, out io.Writer) outcome {
+	cfg := p.cfg
+	repoURL := fmt.Sprintf("%s/%s", cfg.BaseURL, r.url)
+	worktree, repo, err := p.gitManager.CloneAndWorktree(ctx, repoURL, cfg.Branch)
+	if err != nil {
+		return r.fail(out, report.CategoryClone, "clone repo", err)
+	}
+
+	backoff := cfg.PushBackoff
+	for attempt := 1; ; attempt++ {
+		o, err := p.apply(ctx, r, repo, worktree, out)
+		if !errors.Is(err, gitops.ErrPushRejected) {
+			return o
+		}
+		if attempt >= cfg.PushAttempts {
+			return r.fail(out, report.CategoryPush, fmt.Sprintf("commit/push after %d attempts", attempt), err)
+		}
+		fmt.Fprintf(out, "Push rejected, retrying in %s (attempt %d of %d)\n", backoff, attempt+1, cfg.PushAttempts)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return r.fail(out, report.CategoryPush, "commit/push", ctx.Err())
+		}
+		backoff *= 2
+		if err := p.gitManager.FetchAndReset(ctx, repo, worktree, cfg.Branch); err != nil {
+			return r.fail(out, report.CategoryPush, "fetch repo", err)
+		}
+	}
+}
+
+// apply patches the target file of worktree, then commits and pushes it.
+// A rejected push is returned as gitops.ErrPushRejected without being
+// written to out, so that process can retry on the fetched branch.
+func (p *processor) apply(ctx context.Context, r *result, repo *git.Repository, worktree *git.Worktree, out io.Writer) (outcome, error) {
+	cfg := p.cfg
+	targetPath := filepath.Join("overlays", cfg.Env, "patches", "set_resources.yaml")
+	file, err := p.gitManager.GetFile(ctx, worktree, targetPath)
+	if err != nil {
+		return r.fail(out, report.CategoryRead, "read file", err), nil
+	}
+
+	resCfg, ok := p.repoResCfgs[r.url]
+	if !ok {
+		resCfg = p.resCfg
+	}
+	resCfg.Warnings = out
+	manifest, err := p.patcher.Patch(ctx, file, resCfg)
+	if err != nil {
+		return r.fail(out, report.CategoryPatch, "update resource", err), nil
+	}
+
+	if upToDate(p.lister, file, manifest) {
+		fmt.Fprintln(out, "Already up to date")
+		return outcomeUnchanged, nil
+	}
+
+	r.changes = changes(p.lister, file, manifest)
+	if err := p.validator.Validate(manifest); err != nil {
+		fmt.Fprintf(out, "Guardrail violation, skipping repository:\n%v\n", err)
+		r.category, r.err = report.CategoryGuardrail, err
+		return outcomeSkipped, nil
+	}
+
+	if cfg.DryRun {
+		unified, err := diff.Unified(targetPath, file, manifest)
+		if err != nil {
+			return r.fail(out, report.CategoryDiff, "diff file", err), nil
+		}
+		if unified == "" {
+			fmt.Fprintln(out, "No changes")
+			return outcomeUnchanged, nil
+		}
+		if err := writeDiff(out, cfg.DiffDir, r.url, unified); err != nil {
+			fmt.Fprintf(out, "Failed to write diff: %v\n", err)
+		}
+		return outcomeWouldChange, nil
+	}
+
+	message, err := p.commitTemplate.Render(commitmsg.Data{
+		Repository: r.url,
+		Env:        cfg.Env,
+		Path:       targetPath,
+		RunID:      p.runID,
+		Changes:    r.changes,
+	}, p.trailers)
+	if err != nil {
+		return r.fail(out, report.CategoryRender, "render commit message", err), nil
+	}
+
+	// Past this point the repository is finished even if the run is
+	// interrupted, so that no commit is left unpushed and no branch without
+	// its merge request. Deadlines still apply.
+	if err := ctx.Err(); err != nil {
+		return r.fail(out, report.CategoryPush, "commit/push", err), nil
+	}
+	ctx, cancel := withoutCancel(ctx)
+	defer cancel()
+
+	f, err := worktree.Filesystem.Create(targetPath)
+	if err != nil {
+		return r.fail(out, report.CategoryWrite, "open file for writing", err), nil
+	}
+	_, err = f.Write(manifest)
+	if err != nil {
+		f.Close()
+		return r.fail(out, report.CategoryWrite, "write updated YAML", err), nil
+	}
+	f.Close()
+
+	if cfg.MergeRequest {
+		err = p.gitManager.CommitAndPushBranch(ctx, repo, worktree, targetPath, p.mrBranch, message)
+	} else {
+		err = p.gitManager.CommitAndPush(ctx, repo, worktree, targetPath, message)
+	}
+	if errors.Is(err, gitops.ErrNoChanges) {
+		fmt.Fprintln(out, "Already up to date")
+		return outcomeUnchanged, nil
+	}
+	if errors.Is(err, gitops.ErrPushRejected) {
+		return outcomeFailed, err
+	}
+	if err != nil {
+		return r.fail(out, report.CategoryPush, "commit/push", err), nil
+	}
+	if head, err := repo.Head(); err == nil {
+		r.commit = head.Hash().String()
+	}
+
+	if cfg.MergeRequest {
+		unified, err := diff.Unified(targetPath, file, manifest)
+		if err != nil {
+			return r.fail(out, report.CategoryDiff, "diff file", err), nil
+		}
+		mrURL, err := p.opener.OpenChangeRequest(ctx, provider.ChangeRequest{
+			Repository:   r.url,
+			SourceBranch: p.mrBranch,
+			TargetBranch: p.mrTarget,
+			Title:        cfg.MRTitle,
+			Description:  mergeRequestDescription(cfg.MRDescription, unified),
+			Labels:       cfg.MRLabels,
+			Assignees:    cfg.MRAssignees,
+		})
+		if err != nil {
+			return r.fail(out, report.CategoryMergeRequest, "open merge request", err), nil
+		}
+		r.mergeRequest = mrURL
+		fmt.Fprintln(out, "Opened merge request:", mrURL)
+		return outcomeUpdated, nil
+	}
+
+	fmt.Fprintf(out, "Updated file content and pushed to remote!!!\n")
+	return outcomeUpdated, nil
+}
+
+// withoutCancel returns a context that is not canceled with ctx but keeps its
+// deadline.
+func withoutCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	}
+	return context.WithCancel(context.WithoutCancel(ctx))
+}
+
+// printSummary prints the number of repositories per outcome and lists the
+// repositories that failed or were skipped.
+func printSummary(results []result) {
+	counts := map[outcome]int{}
+	var failed, skipped []string
+	for _, r := range results {
+		counts[r.outcome]++
+		switch r.outcome {
+		case outcomeFailed:
+			failed = append(failed, r.url)
+		case outcomeSkipped:
+			skipped = append(skipped, r.url)
+		}
+	}
+
+	var parts []string
+	for _, o := range []outcome{outcomeUpdated, outcomeWouldChange, outcomeUnchanged, outcomeSkipped, outcomeFailed, outcomeCanceled} {
+		if counts[o] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[o], o))
+		}
+	}
+	fmt.Printf("Summary: %d repositories: %s\n", len(results), strings.Join(parts, ", "))
+	if len(skipped) > 0 {
+		fmt.Println("Skipped:", strings.Join(skipped, ", "))
+	}
+	if len(failed) > 0 {
+		fmt.Println("Failed:", strings.Join(failed, ", "))
+	}
+}
+
+// writeDiff writes the diff of repository url to out, or to dir/<url>.diff
+// when dir is set.
+func writeDiff(out io.Writer, dir, url, unified string) error {
+	if dir == "" {
+		fmt.Fprint(out, unified)
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, strings.ReplaceAll(url, "/", "_")+".diff")
+	if err := os.WriteFile(name, []byte(unified), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Diff written to", name)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/commitmsg"
+	"k8s-resource-adjustment/internal/config"
+	"k8s-resource-adjustment/internal/gitops"
+	"k8s-resource-adjustment/internal/guardrails"
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const testManifestPath = "overlays/dev/patches/set_resources.yaml"
+
+// testManifest returns a deployment of image with the given CPU request.
+func testManifest(image, cpu string) string {
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        image: ` + image + `
+        resources:
+          requests:
+            cpu: ` + cpu + `
+`
+}
+
+// setupRemote returns the directory of a bare repository whose master
+// branch holds manifest at testManifestPath.
+func setupRemote(t *testing.T, manifest string) string {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(testManifestPath)), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, testManifestPath), []byte(manifest), 0o644))
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = w.Add(testManifestPath)
+	require.NoError(t, err)
+	_, err = w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Tester", Email: "tester@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	bare := filepath.Join(t.TempDir(), "api.git")
+	_, err = git.PlainClone(bare, true, &git.CloneOptions{URL: "file://" + dir})
+	require.NoError(t, err)
+	return bare
+}
+
+// movingBranchManager pushes the next of theirs to the remote before each
+// push, so that the push is rejected until theirs is exhausted.
+type movingBranchManager struct {
+	*gitops.InMemoryGitRepoManager
+	t      *testing.T
+	url    string
+	theirs []string
+	pushes int
+}
+
+func (m *movingBranchManager) CommitAndPush(ctx context.Context, repo *git.Repository, worktree *git.Worktree, filePath, message string) error {
+	m.pushes++
+	if len(m.theirs) > 0 {
+		worktree, repo, err := m.CloneAndWorktree(ctx, m.url, "refs/heads/master")
+		require.NoError(m.t, err)
+		f, err := worktree.Filesystem.Create(testManifestPath)
+		require.NoError(m.t, err)
+		_, err = f.Write([]byte(m.theirs[0]))
+		require.NoError(m.t, err)
+		require.NoError(m.t, f.Close())
+		require.NoError(m.t, m.InMemoryGitRepoManager.CommitAndPush(ctx, repo, worktree, testManifestPath, "Their change"))
+		m.theirs = m.theirs[1:]
+	}
+	return m.InMemoryGitRepoManager.CommitAndPush(ctx, repo, worktree, filePath, message)
+}
+
+// newTestProcessor returns a processor that sets the CPU request of the
+// repositories next to bare to 500m with up to attempts pushes.
+func newTestProcessor(t *testing.T, bare string, manager *movingBranchManager, attempts int) *processor {
+	patcher := &k8s.NodeResourcePatcher{}
+	commitTemplate, err := commitmsg.New("")
+	require.NoError(t, err)
+	return &processor{
+		cfg: config.Config{
+			Env:          "dev",
+			BaseURL:      "file://" + filepath.Dir(bare),
+			Branch:       "refs/heads/master",
+			PushAttempts: attempts,
+			PushBackoff:  time.Millisecond,
+		},
+		gitManager:     manager,
+		patcher:        patcher,
+		lister:         patcher,
+		validator:      &guardrails.ResourceValidator{Lister: patcher},
+		resCfg:         k8s.ResourceConfig{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+		commitTemplate: commitTemplate,
+	}
+}
+
+func TestProcessor_ProcessWithRetries(t *testing.T) {
+	bare := setupRemote(t, testManifest("api:v1", "100m"))
+	manager := &movingBranchManager{
+		InMemoryGitRepoManager: &gitops.InMemoryGitRepoManager{},
+		t:                      t,
+		url:                    "file://" + bare,
+		theirs:                 []string{testManifest("api:v2", "100m"), testManifest("api:v3", "200m")},
+	}
+	proc := newTestProcessor(t, bare, manager, 3)
+
+	var out bytes.Buffer
+	r := result{url: filepath.Base(bare)}
+	got := proc.processWithRetries(t.Context(), &r, &out)
+	require.Equal(t, outcomeUpdated, got, out.String())
+	assert.Equal(t, 3, manager.pushes)
+	assert.Contains(t, out.String(), "Push rejected, retrying in 1ms (attempt 2 of 3)\n")
+	assert.Contains(t, out.String(), "Push rejected, retrying in 2ms (attempt 3 of 3)\n")
+
+	// The last attempt patched the content pushed by the other writer.
+	worktree, _, err := manager.CloneAndWorktree(t.Context(), manager.url, "refs/heads/master")
+	require.NoError(t, err)
+	data, err := manager.GetFile(t.Context(), worktree, testManifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, testManifest("api:v3", "500m"), string(data))
+	require.Len(t, r.changes, 1)
+	assert.Equal(t, []commitmsg.Value{{Field: "requests.cpu", Old: "200m", New: "500m"}}, r.changes[0].Values)
+}
+
+func TestProcessor_ProcessWithRetriesGivesUp(t *testing.T) {
+	bare := setupRemote(t, testManifest("api:v1", "100m"))
+	manager := &movingBranchManager{
+		InMemoryGitRepoManager: &gitops.InMemoryGitRepoManager{},
+		t:                      t,
+		url:                    "file://" + bare,
+		theirs:                 []string{testManifest("api:v2", "100m"), testManifest("api:v3", "100m")},
+	}
+	proc := newTestProcessor(t, bare, manager, 2)
+
+	var out bytes.Buffer
+	r := result{url: filepath.Base(bare)}
+	got := proc.processWithRetries(t.Context(), &r, &out)
+	assert.Equal(t, outcomeFailed, got)
+	assert.Equal(t, 2, manager.pushes)
+	assert.ErrorIs(t, r.err, gitops.ErrPushRejected)
+	assert.Contains(t, out.String(), "Failed to commit/push after 2 attempts")
+}
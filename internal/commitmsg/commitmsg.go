@@ -0,0 +1,143 @@
+package commitmsg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultTemplate lists the changed values of every container.
+const DefaultTemplate = `Adjust resources of {{.Repository}} in {{.Env}}
+
+Update {{.Path}}:
+{{range .Changes}}
+{{.Kind}}/{{.Name}} container {{.Container}}:
+{{- range .Values}}
+  {{.Field}}: {{or .Old "unset"}} -> {{or .New "unset"}}
+{{- end}}
+{{end}}
+Run: {{.RunID}}
+`
+
+// Data is passed to commit message templates.
+type Data struct {
+	// Repository is the repository as listed in REPO_URLS.
+	Repository string
+	Env        string
+	Path       string
+	RunID      string
+	Changes    []Change
+}
+
+// Change holds the changed values of a single container.
+type Change struct {
+	Kind      string  `json:"kind"`
+	Name      string  `json:"name"`
+	Container string  `json:"container"`
+	Values    []Value `json:"values"`
+}
+
+// Value is a changed resource value such as "limits.cpu". Old or New is
+// empty when the value is added or removed.
+type Value struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// sampleData is rendered by New, so that unknown fields and invalid calls
+// are reported before any repository is processed.
+var sampleData = Data{
+	Repository: "group/repository",
+	Env:        "env",
+	Path:       "path",
+	RunID:      "run",
+	Changes: []Change{{
+		Kind:      "Deployment",
+		Name:      "app",
+		Container: "app",
+		Values:    []Value{{Field: "requests.cpu", Old: "100m", New: "200m"}},
+	}},
+}
+
+// Template renders commit messages.
+type Template struct {
+	tmpl *template.Template
+}
+
+// New parses a commit message template and renders it once with sample data;
+// an empty text selects DefaultTemplate.
+func New(text string) (*Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("commit").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, sampleData); err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render returns the message for data followed by trailers such as
+// "Signed-off-by: Jane <jane@example.com>", separated by a blank line.
+func (t *Template) Render(data Data, trailers []string) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+	message := strings.TrimRight(sb.String(), "\n") + "\n"
+	if len(trailers) > 0 {
+		message += "\n" + strings.Join(trailers, "\n") + "\n"
+	}
+	return message, nil
+}
+
+// Changes pairs the containers of before and after and returns the values
+// that differ, in the order of after. Quantities are compared semantically.
+func Changes(before, after []k8s.ContainerResources) []Change {
+	old := make(map[string]k8s.ContainerResources, len(before))
+	for _, c := range before {
+		old[c.String()] = c
+	}
+
+	var changes []Change
+	for _, c := range after {
+		prev := old[c.String()]
+		var values []Value
+		for _, section := range []struct {
+			name     string
+			old, new corev1.ResourceList
+		}{
+			{"requests", prev.Requests, c.Requests},
+			{"limits", prev.Limits, c.Limits},
+		} {
+			for _, name := range k8s.ResourceNames(section.old, section.new) {
+				o, hadOld := section.old[name]
+				n, hasNew := section.new[name]
+				if hadOld == hasNew && o.Cmp(n) == 0 {
+					continue
+				}
+				v := Value{Field: section.name + "." + string(name)}
+				if hadOld {
+					v.Old = o.String()
+				}
+				if hasNew {
+					v.New = n.String()
+				}
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			changes = append(changes, Change{Kind: c.Kind, Name: c.Name, Container: c.Container, Values: values})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,104 @@
+package commitmsg_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/commitmsg"
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestChanges(t *testing.T) {
+	before := []k8s.ContainerResources{
+		{Kind: "Deployment", Name: "api", Container: "app",
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+		{Kind: "Deployment", Name: "api", Container: "sidecar",
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}},
+	}
+	after := []k8s.ContainerResources{
+		{Kind: "Deployment", Name: "api", Container: "app",
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.2"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}},
+		{Kind: "Deployment", Name: "api", Container: "sidecar",
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.01")}},
+	}
+
+	assert.Equal(t, []commitmsg.Change{{
+		Kind: "Deployment", Name: "api", Container: "app",
+		Values: []commitmsg.Value{
+			{Field: "requests.cpu", Old: "100m", New: "200m"},
+			{Field: "limits.cpu", Old: "1"},
+			{Field: "limits.memory", New: "256Mi"},
+		},
+	}}, commitmsg.Changes(before, after))
+}
+
+func TestTemplate_Render(t *testing.T) {
+	data := commitmsg.Data{
+		Repository: "platform/api",
+		Env:        "prod",
+		Path:       "overlays/prod/patches/set_resources.yaml",
+		RunID:      "20250101-120000",
+		Changes: []commitmsg.Change{{
+			Kind: "Deployment", Name: "api", Container: "app",
+			Values: []commitmsg.Value{
+				{Field: "requests.cpu", Old: "100m", New: "200m"},
+				{Field: "limits.memory", New: "256Mi"},
+			},
+		}},
+	}
+
+	t.Run("default template", func(t *testing.T) {
+		tmpl, err := commitmsg.New("")
+		require.NoError(t, err)
+		msg, err := tmpl.Render(data, []string{"Signed-off-by: Jane <jane@example.com>", "Ticket: OPS-1"})
+		require.NoError(t, err)
+		assert.Equal(t, `Adjust resources of platform/api in prod
+
+Update overlays/prod/patches/set_resources.yaml:
+
+Deployment/api container app:
+  requests.cpu: 100m -> 200m
+  limits.memory: unset -> 256Mi
+
+Run: 20250101-120000
+
+Signed-off-by: Jane <jane@example.com>
+Ticket: OPS-1
+`, msg)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		tmpl, err := commitmsg.New(`chore({{.Env}}): resize {{len .Changes}} container(s) [{{.RunID}}]`)
+		require.NoError(t, err)
+		msg, err := tmpl.Render(data, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "chore(prod): resize 1 container(s) [20250101-120000]\n", msg)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := commitmsg.New(`{{.Env`)
+		assert.ErrorContains(t, err, "invalid commit message template")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := commitmsg.New(`{{.Cluster}}`)
+		assert.ErrorContains(t, err, "invalid commit message template")
+		assert.ErrorContains(t, err, "can't evaluate field Cluster")
+	})
+
+	t.Run("unknown field of a change", func(t *testing.T) {
+		_, err := commitmsg.New(`{{range .Changes}}{{range .Values}}{{.Quantity}}{{end}}{{end}}`)
+		assert.ErrorContains(t, err, "can't evaluate field Quantity")
+	})
+
+	t.Run("invalid call", func(t *testing.T) {
+		_, err := commitmsg.New(`{{len .Env 2}}`)
+		assert.ErrorContains(t, err, "invalid commit message template")
+	})
+}
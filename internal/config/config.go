@@ -1,15 +1,22 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // ConfigLoader is responsible for loading configuration from environment variables
 type ConfigLoader interface {
-	Load() Config
+	// Load returns the configuration, or every problem found in it joined
+	// with errors.Join.
+	Load(ctx context.Context) (Config, error)
 }
 
 type Config struct {
@@ -21,32 +28,388 @@ type Config struct {
 	MemLimit   string
 	CPURequest string
 	MemRequest string
+	// Requests and Limits hold further resources as name=quantity pairs,
+	// e.g. "ephemeral-storage=1Gi,nvidia.com/gpu=1"; see
+	// k8s.ParseResourceList. The CPU and memory values above take
+	// precedence over cpu and memory entries.
+	Requests string
+	Limits   string
+	// RemoveResources lists resources entries to delete, e.g. "limits.cpu".
+	RemoveResources []string
+	// Adjustments change resources relative to their current values, e.g.
+	// "limits.memory*1.25,limits.cpu=2*requests.cpu"; see
+	// k8s.ParseAdjustments. Rounding holds the units adjusted values are
+	// rounded up to, as name=quantity pairs such as "cpu=10m,memory=16Mi".
+	Adjustments string
+	Rounding    string
+	// Containers lists the container selectors (names, globs or /regexes/)
+	// the resource values are applied to. Empty means the first app container.
+	Containers []string
+	// InitContainers and SidecarContainers select init containers and
+	// native sidecars (init containers with restartPolicy: Always). Their
+	// resource values fall back to the ones above when empty.
+	InitContainers    []string
+	InitCPULimit      string
+	InitMemLimit      string
+	InitCPURequest    string
+	InitMemRequest    string
+	InitRequests      string
+	InitLimits        string
+	SidecarContainers []string
+	SidecarCPULimit   string
+	SidecarMemLimit   string
+	SidecarCPURequest string
+	SidecarMemRequest string
+	SidecarRequests   string
+	SidecarLimits     string
+	// MinResources, MaxResources and MaxLimitRequestRatio are guardrails
+	// checked before a patched manifest is written, as name=quantity pairs
+	// such as "cpu=10m,memory=16Mi".
+	MinResources         string
+	MaxResources         string
+	MaxLimitRequestRatio string
+	// DryRun clones and patches every repository but, instead of pushing,
+	// prints a unified diff of the change or writes it to DiffDir.
+	DryRun  bool
+	DiffDir string
+	// MergeRequest pushes to a new branch named MRBranchPrefix<env>-<time>
+	// and opens a merge request (GitLab) or pull request (GitHub) into
+	// MRTargetBranch, which defaults to Branch, instead of pushing to Branch.
+	MergeRequest bool
+	// Provider is the Git hosting provider, "gitlab" or "github".
+	Provider       string
+	GitLabBaseURL  string
+	GitLabToken    string
+	GitHubBaseURL  string
+	GitHubToken    string
+	MRBranchPrefix string
+	MRTargetBranch string
+	MRTitle        string
+	// MRDescription is followed by the diff of the change.
+	MRDescription string
+	MRLabels      []string
+	// MRAssignees are usernames.
+	MRAssignees []string
+	// GitToken, GitUsername and GitPassword authenticate HTTP(S) clones
+	// and pushes. GitToken falls back to the token of Provider; without
+	// either, the .netrc entry of the host at NetrcPath is used.
+	GitToken    string
+	GitUsername string
+	GitPassword string
+	NetrcPath   string
+	// SSHKeyPath is the private key for SSH remotes, decrypted with
+	// SSHKeyPassphrase. Servers are verified against SSHKnownHosts.
+	SSHKeyPath       string
+	SSHKeyPassphrase string
+	SSHKnownHosts    string
+	// CommitMessageTemplate is a Go template for commit messages, read from
+	// CommitMessageTemplateFile when that is set; see commitmsg.Data for
+	// its fields. Empty selects commitmsg.DefaultTemplate.
+	CommitMessageTemplate     string
+	CommitMessageTemplateFile string
+	// AuthorName and AuthorEmail identify the commit author, which defaults
+	// to gitops.DefaultIdentity; the committer defaults to the author.
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+	// SignOff adds a Signed-off-by trailer for the author, followed by
+	// CommitTrailers such as "Ticket: OPS-1".
+	SignOff        bool
+	CommitTrailers []string
+	// SigningKey, or the contents of SigningKeyFile, signs commits when
+	// set. SigningFormat is "openpgp" for an armored OpenPGP private key or
+	// "ssh" for an SSH private key; either may be encrypted with
+	// SigningKeyPassphrase.
+	SigningFormat        string
+	SigningKey           string
+	SigningKeyFile       string
+	SigningKeyPassphrase string
+	// PushAttempts bounds the attempts to push when the push is rejected
+	// because the branch moved. Before each retry the branch is fetched and
+	// the file patched again, after PushBackoff, doubled on every retry.
+	PushAttempts int
+	PushBackoff  time.Duration
+	// Timeout bounds the whole run and RepoTimeout the processing of each
+	// repository; zero means no limit.
+	Timeout     time.Duration
+	RepoTimeout time.Duration
+	// ReportJSONFile, ReportMarkdownFile and ReportJUnitFile are the files
+	// the run report is written to in each format; empty skips the format.
+	ReportJSONFile     string
+	ReportMarkdownFile string
+	ReportJUnitFile    string
+	// Workers is the number of repositories processed in parallel.
+	Workers int
+	// FailureThreshold stops the run once more repositories failed than a
+	// number such as "5" or a percentage of REPO_URLS such as "10%"; empty
+	// means no limit.
+	FailureThreshold string
+	// RunID identifies the run in commit messages and branch names; empty
+	// means the start time.
+	RunID string
+	// CustomKinds registers additional kinds and their container paths, see
+	// k8s.ParseCustomKinds for the syntax.
+	CustomKinds string
+	// Repositories overrides the resource values above for single
+	// repositories, keyed by their REPO_URLS entry. It is only set by
+	// FileConfigLoader.
+	Repositories map[string]Resources
+
+	// Parsed holds the values above parsed for use. The loaders set it from
+	// Validate.
+	Parsed Parsed
+}
+
+// Resources holds resource values in the format of the Config fields of the
+// same name. Empty values keep the value they override.
+type Resources struct {
+	CPULimit        string   `yaml:"cpu_limit"`
+	MemLimit        string   `yaml:"mem_limit"`
+	CPURequest      string   `yaml:"cpu_request"`
+	MemRequest      string   `yaml:"mem_request"`
+	Requests        string   `yaml:"requests"`
+	Limits          string   `yaml:"limits"`
+	RemoveResources []string `yaml:"remove_resources"`
+	Adjustments     string   `yaml:"adjustments"`
+	// Containers patches the app containers matched by each selector with
+	// their own values, in addition to the ones selected by
+	// Config.Containers, whose entry of the same selector it replaces.
+	Containers map[string]Resources `yaml:"containers"`
+}
+
+// merge returns r with the non-empty values of o, merging containers of
+// the same selector.
+func (r Resources) merge(o Resources) Resources {
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{
+		{&r.CPULimit, o.CPULimit},
+		{&r.MemLimit, o.MemLimit},
+		{&r.CPURequest, o.CPURequest},
+		{&r.MemRequest, o.MemRequest},
+		{&r.Requests, o.Requests},
+		{&r.Limits, o.Limits},
+		{&r.Adjustments, o.Adjustments},
+	} {
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+	if o.RemoveResources != nil {
+		r.RemoveResources = o.RemoveResources
+	}
+	if len(o.Containers) > 0 {
+		containers := make(map[string]Resources, len(r.Containers)+len(o.Containers))
+		for name, c := range r.Containers {
+			containers[name] = c
+		}
+		for name, c := range o.Containers {
+			containers[name] = containers[name].merge(c)
+		}
+		r.Containers = containers
+	}
+	return r
+}
+
+// WithResources returns c with its resource values replaced by the
+// non-empty values of r. The containers of r are ignored.
+func (c Config) WithResources(r Resources) Config {
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{
+		{&c.CPULimit, r.CPULimit},
+		{&c.MemLimit, r.MemLimit},
+		{&c.CPURequest, r.CPURequest},
+		{&c.MemRequest, r.MemRequest},
+		{&c.Requests, r.Requests},
+		{&c.Limits, r.Limits},
+		{&c.Adjustments, r.Adjustments},
+	} {
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+	if r.RemoveResources != nil {
+		c.RemoveResources = r.RemoveResources
+	}
+	return c
+}
+
+// NewConfigLoader loads the .env file into the environment, if there is
+// one, and returns a FileConfigLoader for the file named by CONFIG_FILE, or
+// an EnvConfigLoader when it is unset.
+func NewConfigLoader() ConfigLoader {
+	_ = godotenv.Load()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return &FileConfigLoader{Path: path}
+	}
+	return &EnvConfigLoader{}
 }
 
 type EnvConfigLoader struct{}
 
-func getEnv(key, defaultVal string) string {
-	if val, ok := os.LookupEnv(key); ok && val != "" {
+// parser reads typed variables from lookup and collects the errors of
+// invalid values.
+type parser struct {
+	lookup func(key string) (string, bool)
+	errs   []error
+}
+
+func (p *parser) getEnv(key, defaultVal string) string {
+	if val, ok := p.lookup(key); ok && val != "" {
 		return val
 	}
 	return defaultVal
 }
 
-func (e *EnvConfigLoader) Load() Config {
-	_ = godotenv.Load()
-	repoURLs := getEnv("REPO_URLS", "__URL_1__,__URL_2__")
-	urls := []string{}
-	for _, url := range strings.Split(repoURLs, ",") {
-		urls = append(urls, strings.TrimSpace(url))
+// getList splits a comma-separated variable, dropping empty entries.
+func (p *parser) getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(p.getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getBool reports whether a variable is set to a true value such as "true"
+// or "1".
+func (p *parser) getBool(key string) bool {
+	b, err := strconv.ParseBool(p.getEnv(key, "false"))
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: invalid boolean %q", key, p.getEnv(key, "")))
 	}
+	return b
+}
+
+// getInt returns a variable as an integer, or defaultVal if it is unset.
+func (p *parser) getInt(key string, defaultVal int) int {
+	val := p.getEnv(key, "")
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: invalid number %q", key, val))
+		return defaultVal
+	}
+	return n
+}
+
+// getDuration returns a variable as a duration such as "500ms", or
+// defaultVal if it is unset.
+func (p *parser) getDuration(key string, defaultVal time.Duration) time.Duration {
+	val := p.getEnv(key, "")
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: invalid duration %q", key, val))
+		return defaultVal
+	}
+	return d
+}
+
+func (e *EnvConfigLoader) Load(ctx context.Context) (Config, error) {
+	_ = godotenv.Load()
+	p := &parser{lookup: os.LookupEnv}
+	cfg := p.config()
+	parsed, err := cfg.Validate()
+	cfg.Parsed = parsed
+	return cfg, errors.Join(append(p.errs, err)...)
+}
+
+// config reads the configuration.
+func (p *parser) config() Config {
 	return Config{
-		Env:        getEnv("ENV", "__ENV__"),
-		BaseURL:    getEnv("BASE_URL", "__GIT_URL__"),
-		Branch:     getEnv("BRANCH", "__BRANCH__"),
-		RepoURLs:   urls,
-		CPULimit:   getEnv("CPU_LIMIT", "20m"),
-		MemLimit:   getEnv("MEM_LIMIT", "32Mi"),
-		CPURequest: getEnv("CPU_REQUEST", "10m"),
-		MemRequest: getEnv("MEM_REQUEST", "16Mi"),
+		Env:        p.getEnv("ENV", ""),
+		BaseURL:    p.getEnv("BASE_URL", ""),
+		Branch:     p.getEnv("BRANCH", ""),
+		RepoURLs:   p.getList("REPO_URLS"),
+		CPULimit:   p.getEnv("CPU_LIMIT", ""),
+		MemLimit:   p.getEnv("MEM_LIMIT", ""),
+		CPURequest: p.getEnv("CPU_REQUEST", ""),
+		MemRequest: p.getEnv("MEM_REQUEST", ""),
+		Requests:   p.getEnv("REQUESTS", ""),
+		Limits:     p.getEnv("LIMITS", ""),
+		Containers: p.getList("CONTAINERS"),
+
+		RemoveResources: p.getList("REMOVE_RESOURCES"),
+		Adjustments:     p.getEnv("ADJUSTMENTS", ""),
+		Rounding:        p.getEnv("ROUNDING", ""),
+
+		InitContainers:    p.getList("INIT_CONTAINERS"),
+		InitCPULimit:      p.getEnv("INIT_CPU_LIMIT", ""),
+		InitMemLimit:      p.getEnv("INIT_MEM_LIMIT", ""),
+		InitCPURequest:    p.getEnv("INIT_CPU_REQUEST", ""),
+		InitMemRequest:    p.getEnv("INIT_MEM_REQUEST", ""),
+		InitRequests:      p.getEnv("INIT_REQUESTS", ""),
+		InitLimits:        p.getEnv("INIT_LIMITS", ""),
+		SidecarContainers: p.getList("SIDECAR_CONTAINERS"),
+		SidecarCPULimit:   p.getEnv("SIDECAR_CPU_LIMIT", ""),
+		SidecarMemLimit:   p.getEnv("SIDECAR_MEM_LIMIT", ""),
+		SidecarCPURequest: p.getEnv("SIDECAR_CPU_REQUEST", ""),
+		SidecarMemRequest: p.getEnv("SIDECAR_MEM_REQUEST", ""),
+		SidecarRequests:   p.getEnv("SIDECAR_REQUESTS", ""),
+		SidecarLimits:     p.getEnv("SIDECAR_LIMITS", ""),
+		CustomKinds:       p.getEnv("CUSTOM_KINDS", ""),
+
+		MinResources:         p.getEnv("MIN_RESOURCES", ""),
+		MaxResources:         p.getEnv("MAX_RESOURCES", ""),
+		MaxLimitRequestRatio: p.getEnv("MAX_LIMIT_REQUEST_RATIO", ""),
+
+		DryRun:  p.getBool("DRY_RUN"),
+		DiffDir: p.getEnv("DIFF_DIR", ""),
+
+		MergeRequest:   p.getBool("MERGE_REQUEST"),
+		GitLabBaseURL:  p.getEnv("GITLAB_BASE_URL", "https://gitlab.com"),
+		GitLabToken:    p.getEnv("GITLAB_TOKEN", ""),
+		Provider:       p.getEnv("PROVIDER", "gitlab"),
+		GitHubBaseURL:  p.getEnv("GITHUB_BASE_URL", "https://api.github.com"),
+		GitHubToken:    p.getEnv("GITHUB_TOKEN", ""),
+		MRBranchPrefix: p.getEnv("MR_BRANCH_PREFIX", "resource-adjustment/"),
+		MRTargetBranch: p.getEnv("MR_TARGET_BRANCH", ""),
+		MRTitle:        p.getEnv("MR_TITLE", "Adjust container resources"),
+		MRDescription:  p.getEnv("MR_DESCRIPTION", ""),
+		MRLabels:       p.getList("MR_LABELS"),
+		MRAssignees:    p.getList("MR_ASSIGNEES"),
+
+		GitToken:         p.getEnv("GIT_TOKEN", ""),
+		GitUsername:      p.getEnv("GIT_USERNAME", ""),
+		GitPassword:      p.getEnv("GIT_PASSWORD", ""),
+		NetrcPath:        p.getEnv("NETRC", ""),
+		SSHKeyPath:       p.getEnv("SSH_KEY_PATH", ""),
+		SSHKeyPassphrase: p.getEnv("SSH_KEY_PASSPHRASE", ""),
+		SSHKnownHosts:    p.getEnv("SSH_KNOWN_HOSTS", ""),
+
+		CommitMessageTemplate:     p.getEnv("COMMIT_MESSAGE_TEMPLATE", ""),
+		CommitMessageTemplateFile: p.getEnv("COMMIT_MESSAGE_TEMPLATE_FILE", ""),
+		AuthorName:                p.getEnv("GIT_AUTHOR_NAME", ""),
+		AuthorEmail:               p.getEnv("GIT_AUTHOR_EMAIL", ""),
+		CommitterName:             p.getEnv("GIT_COMMITTER_NAME", ""),
+		CommitterEmail:            p.getEnv("GIT_COMMITTER_EMAIL", ""),
+		SignOff:                   p.getBool("SIGN_OFF"),
+		CommitTrailers:            p.getList("COMMIT_TRAILERS"),
+		RunID:                     p.getEnv("RUN_ID", ""),
+		Workers:                   p.getInt("WORKERS", 4),
+		FailureThreshold:          p.getEnv("FAILURE_THRESHOLD", ""),
+		PushAttempts:              p.getInt("PUSH_ATTEMPTS", 3),
+		PushBackoff:               p.getDuration("PUSH_BACKOFF", 2*time.Second),
+		Timeout:                   p.getDuration("TIMEOUT", 0),
+		RepoTimeout:               p.getDuration("REPO_TIMEOUT", 10*time.Minute),
+
+		ReportJSONFile:     p.getEnv("REPORT_JSON_FILE", ""),
+		ReportMarkdownFile: p.getEnv("REPORT_MARKDOWN_FILE", ""),
+		ReportJUnitFile:    p.getEnv("REPORT_JUNIT_FILE", ""),
+
+		SigningFormat:        p.getEnv("SIGNING_FORMAT", "openpgp"),
+		SigningKey:           p.getEnv("SIGNING_KEY", ""),
+		SigningKeyFile:       p.getEnv("SIGNING_KEY_FILE", ""),
+		SigningKeyPassphrase: p.getEnv("SIGNING_KEY_PASSPHRASE", ""),
 	}
 }
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfigLoader loads the configuration from a YAML or JSON file such as
+//
+//	defaults:
+//	  base_url: https://gitlab.com/acme
+//	  branch: refs/heads/main
+//	  cpu_request: 100m
+//	environments:
+//	  prod:
+//	    cpu_request: 250m
+//	    repositories:
+//	      platform/api:
+//	        mem_limit: 2Gi
+//	repositories:
+//	  platform/api:
+//	    cpu_request: 500m
+//	    containers:
+//	      worker:
+//	        cpu_request: "1"
+//
+// The keys of defaults and environments are the names of the environment
+// variables read by EnvConfigLoader, in any case. Each key is taken from,
+// in decreasing precedence, the environment variable, the environment
+// named by ENV, the defaults and the built-in default. The resource values
+// of a repository are then taken from, in decreasing precedence, the
+// container, the repository in the environment named by ENV, the
+// repository and the keys above. REPO_URLS defaults to the repositories of
+// the file, sorted. Unlike EnvConfigLoader, the .env file is not read by
+// Load; NewConfigLoader reads it before choosing the loader.
+type FileConfigLoader struct {
+	Path string
+}
+
+// file is the format read by FileConfigLoader.
+type file struct {
+	Defaults     map[string]any         `yaml:"defaults"`
+	Environments map[string]environment `yaml:"environments"`
+	Repositories map[string]Resources   `yaml:"repositories"`
+}
+
+type environment struct {
+	Values       map[string]any       `yaml:",inline"`
+	Repositories map[string]Resources `yaml:"repositories"`
+}
+
+func (l *FileConfigLoader) Load(ctx context.Context) (Config, error) {
+	data, err := os.ReadFile(l.Path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", l.Path, err)
+	}
+
+	var errs []error
+	defaults, err := values(f.Defaults)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("defaults: %w", err))
+	}
+	env, ok := os.LookupEnv("ENV")
+	if !ok || env == "" {
+		env = defaults["ENV"]
+	}
+	var envValues map[string]string
+	var envRepositories map[string]Resources
+	if e, ok := f.Environments[env]; ok {
+		envValues, err = values(e.Values)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("environments.%s: %w", env, err))
+		}
+		envRepositories = e.Repositories
+	}
+
+	repositories := make(map[string]Resources, len(f.Repositories)+len(envRepositories))
+	for name, r := range f.Repositories {
+		repositories[name] = r
+	}
+	for name, r := range envRepositories {
+		repositories[name] = repositories[name].merge(r)
+	}
+	names := slices.Sorted(maps.Keys(repositories))
+
+	read := map[string]bool{}
+	p := &parser{lookup: func(key string) (string, bool) {
+		read[key] = true
+		if val, ok := os.LookupEnv(key); ok && val != "" {
+			return val, true
+		}
+		if val, ok := envValues[key]; ok {
+			return val, true
+		}
+		if val, ok := defaults[key]; ok {
+			return val, true
+		}
+		if key == "REPO_URLS" && len(names) > 0 {
+			return strings.Join(names, ","), true
+		}
+		return "", false
+	}}
+	cfg := p.config()
+	if len(repositories) > 0 {
+		cfg.Repositories = repositories
+	}
+
+	for _, scope := range []struct {
+		name   string
+		values map[string]string
+	}{
+		{"defaults", defaults},
+		{"environments." + env, envValues},
+	} {
+		for _, key := range slices.Sorted(maps.Keys(scope.values)) {
+			if !read[key] {
+				errs = append(errs, fmt.Errorf("%s: unknown key %s", scope.name, strings.ToLower(key)))
+			}
+		}
+	}
+	errs = append(errs, p.errs...)
+	parsed, err := cfg.Validate()
+	cfg.Parsed = parsed
+	return cfg, errors.Join(append(errs, err)...)
+}
+
+// values returns the values of a defaults or environment section keyed by
+// environment variable name, with lists joined by commas.
+func values(section map[string]any) (map[string]string, error) {
+	vals := make(map[string]string, len(section))
+	for key, v := range section {
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			vals[strings.ToUpper(key)] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: expected a value or a list", key)
+		default:
+			vals[strings.ToUpper(key)] = fmt.Sprint(v)
+		}
+	}
+	return vals, nil
+}
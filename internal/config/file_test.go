@@ -0,0 +1,197 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/config"
+)
+
+// writeConfigFile writes content to a config file and returns its path.
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv unsets the variables read by the tests below for the duration of
+// t.
+func clearEnv(t *testing.T, keys ...string) {
+	for _, key := range keys {
+		t.Setenv(key, "")
+	}
+}
+
+const testConfigFile = `
+defaults:
+  env: dev
+  base_url: https://gitlab.com/acme
+  branch: refs/heads/main
+  cpu_request: 100m
+  mem_limit: 256Mi
+  workers: 8
+  dry_run: true
+  containers: [app, worker-*]
+environments:
+  prod:
+    cpu_request: 250m
+    branch: refs/heads/release
+    repositories:
+      platform/api:
+        mem_limit: 2Gi
+        containers:
+          worker-1:
+            cpu_limit: 4
+repositories:
+  platform/api:
+    cpu_request: 500m
+    mem_limit: 1Gi
+    containers:
+      worker-1:
+        cpu_request: "1"
+  platform/web:
+    remove_resources: [limits.cpu]
+`
+
+func TestFileConfigLoader_Load(t *testing.T) {
+	clearEnv(t, "ENV", "BRANCH", "CPU_REQUEST", "REPO_URLS", "WORKERS")
+	path := writeConfigFile(t, "config.yaml", testConfigFile)
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := (&config.FileConfigLoader{Path: path}).Load(t.Context())
+		if err != nil {
+			t.Fatalf("Load() unexpected error = %v", err)
+		}
+		if cfg.Env != "dev" || cfg.Branch != "refs/heads/main" || cfg.CPURequest != "100m" || cfg.Workers != 8 || !cfg.DryRun {
+			t.Errorf("Load() = %+v, want the defaults of the file", cfg)
+		}
+		if want := []string{"platform/api", "platform/web"}; !reflect.DeepEqual(cfg.RepoURLs, want) {
+			t.Errorf("Load() RepoURLs = %v, want %v", cfg.RepoURLs, want)
+		}
+		if want := []string{"app", "worker-*"}; !reflect.DeepEqual(cfg.Containers, want) {
+			t.Errorf("Load() Containers = %v, want %v", cfg.Containers, want)
+		}
+		if cfg.PushBackoff != 2*time.Second {
+			t.Errorf("Load() PushBackoff = %v, want the built-in default", cfg.PushBackoff)
+		}
+
+		api := cfg.WithResources(cfg.Repositories["platform/api"])
+		if api.CPURequest != "500m" || api.MemLimit != "1Gi" {
+			t.Errorf("WithResources() = %s/%s, want 500m/1Gi", api.CPURequest, api.MemLimit)
+		}
+		worker := api.WithResources(cfg.Repositories["platform/api"].Containers["worker-1"])
+		if worker.CPURequest != "1" || worker.MemLimit != "1Gi" || worker.CPULimit != "" {
+			t.Errorf("WithResources() of worker-1 = %+v", worker)
+		}
+		web := cfg.WithResources(cfg.Repositories["platform/web"])
+		if web.CPURequest != "100m" || !reflect.DeepEqual(web.RemoveResources, []string{"limits.cpu"}) {
+			t.Errorf("WithResources() of platform/web = %+v", web)
+		}
+	})
+
+	t.Run("environment and env vars", func(t *testing.T) {
+		t.Setenv("ENV", "prod")
+		t.Setenv("BRANCH", "refs/heads/hotfix")
+		cfg, err := (&config.FileConfigLoader{Path: path}).Load(t.Context())
+		if err != nil {
+			t.Fatalf("Load() unexpected error = %v", err)
+		}
+		if cfg.Env != "prod" || cfg.CPURequest != "250m" || cfg.Branch != "refs/heads/hotfix" {
+			t.Errorf("Load() = %s/%s/%s, want prod/250m/refs/heads/hotfix", cfg.Env, cfg.CPURequest, cfg.Branch)
+		}
+
+		api := cfg.Repositories["platform/api"]
+		if api.CPURequest != "500m" || api.MemLimit != "2Gi" {
+			t.Errorf("Repositories[platform/api] = %+v, want 500m and the 2Gi of prod", api)
+		}
+		want := config.Resources{CPURequest: "1", CPULimit: "4"}
+		if got := api.Containers["worker-1"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Repositories[platform/api].Containers[worker-1] = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{
+			"defaults": {"ENV": "dev", "BASE_URL": "git@gitlab.com:acme", "BRANCH": "main", "REPO_URLS": "api"},
+			"repositories": {"api": {"cpu_request": "2"}}
+		}`)
+		cfg, err := (&config.FileConfigLoader{Path: path}).Load(t.Context())
+		if err != nil {
+			t.Fatalf("Load() unexpected error = %v", err)
+		}
+		if !reflect.DeepEqual(cfg.RepoURLs, []string{"api"}) || cfg.Repositories["api"].CPURequest != "2" {
+			t.Errorf("Load() = %+v", cfg)
+		}
+	})
+}
+
+func TestFileConfigLoader_LoadInvalid(t *testing.T) {
+	clearEnv(t, "ENV", "BRANCH", "CPU_REQUEST", "REPO_URLS", "WORKERS")
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", "repositories:\n  api:\n    cpu_requst: 1\n")
+		if _, err := (&config.FileConfigLoader{Path: path}).Load(t.Context()); err == nil || !strings.Contains(err.Error(), "cpu_requst") {
+			t.Errorf("Load() error = %v, want an error naming cpu_requst", err)
+		}
+	})
+
+	t.Run("every problem", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+defaults:
+  env: staging
+  base_url: https://gitlab.com/acme
+  branch: main
+  workerz: 4
+  cpu_limit: 200mm
+environments:
+  prod: {}
+repositories:
+  api:
+    mem_request: lots
+    containers:
+      worker:
+        adjustments: limits.cpu^2
+`)
+		_, err := (&config.FileConfigLoader{Path: path}).Load(t.Context())
+		if err == nil {
+			t.Fatal("Load() expected an error, but got nil")
+		}
+		for _, want := range []string{
+			"defaults: unknown key workerz",
+			`CPU_LIMIT: invalid quantity "200mm"`,
+			`repositories.api.mem_request: invalid quantity "lots"`,
+			"repositories.api.containers.worker.adjustments:",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, want)
+			}
+		}
+	})
+}
+
+func TestNewConfigLoader(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", testConfigFile)
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(".env", []byte("CONFIG_FILE="+path+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// t.Setenv restores the variable after the test; it must be unset for
+	// the .env file to set it.
+	t.Setenv("CONFIG_FILE", "")
+	os.Unsetenv("CONFIG_FILE")
+
+	got := config.NewConfigLoader()
+	loader, ok := got.(*config.FileConfigLoader)
+	if !ok {
+		t.Fatalf("NewConfigLoader() = %T, want *config.FileConfigLoader", got)
+	}
+	if loader.Path != path {
+		t.Errorf("NewConfigLoader() path = %q, want %q", loader.Path, path)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// repositoryResourceConfig returns the resources to apply to repository,
+// with its values from Repositories on top of the ones of c.
+func (c Config) repositoryResourceConfig(repository string) (k8s.ResourceConfig, error) {
+	r := c.Repositories[repository]
+	return c.WithResources(r).resourceConfig(r.Containers)
+}
+
+// resourceConfig returns the resources to apply from c. Each entry of
+// containers patches the app containers matched by its selector with its own
+// values on top of the ones of c. An entry replaces the CONTAINERS entry of
+// the same selector; other entries are overrides that leave the containers
+// selected by CONTAINERS, or the first app container, patched with the
+// values of c.
+func (c Config) resourceConfig(containers map[string]Resources) (k8s.ResourceConfig, error) {
+	requests, limits, err := resourceLists(c.Requests, c.Limits, c.CPURequest, c.MemRequest, c.CPULimit, c.MemLimit)
+	if err != nil {
+		return k8s.ResourceConfig{}, err
+	}
+	adjustments, err := k8s.ParseAdjustments(c.Adjustments)
+	if err != nil {
+		return k8s.ResourceConfig{}, fmt.Errorf("ADJUSTMENTS: %w", err)
+	}
+	rounding, err := k8s.ParseResourceList(c.Rounding)
+	if err != nil {
+		return k8s.ResourceConfig{}, fmt.Errorf("ROUNDING: %w", err)
+	}
+	resCfg := k8s.ResourceConfig{
+		Requests:    requests,
+		Limits:      limits,
+		Remove:      c.RemoveResources,
+		Adjustments: adjustments,
+		Rounding:    rounding,
+	}
+
+	selectors := slices.Sorted(maps.Keys(containers))
+	for _, selector := range selectors {
+		o := c.WithResources(containers[selector])
+		requests, limits, err := resourceLists(o.Requests, o.Limits, o.CPURequest, o.MemRequest, o.CPULimit, o.MemLimit)
+		if err != nil {
+			return k8s.ResourceConfig{}, fmt.Errorf("container %s: %w", selector, err)
+		}
+		adjustments, err := k8s.ParseAdjustments(o.Adjustments)
+		if err != nil {
+			return k8s.ResourceConfig{}, fmt.Errorf("container %s: %w", selector, err)
+		}
+		resCfg.Containers = append(resCfg.Containers, k8s.ContainerConfig{
+			Selector:    selector,
+			Type:        k8s.AppContainers,
+			Requests:    requests,
+			Limits:      limits,
+			Remove:      o.RemoveResources,
+			Adjustments: adjustments,
+			Override:    !slices.Contains(c.Containers, selector),
+		})
+	}
+	var appSelectors []string
+	for _, selector := range c.Containers {
+		if !slices.Contains(selectors, selector) {
+			appSelectors = append(appSelectors, selector)
+		}
+	}
+
+	for _, group := range []struct {
+		typ                                        k8s.ContainerType
+		selectors                                  []string
+		requests, limits                           string
+		cpuRequest, memRequest, cpuLimit, memLimit string
+	}{
+		{k8s.AppContainers, appSelectors, "", "", "", "", "", ""},
+		{k8s.InitContainers, c.InitContainers, c.InitRequests, c.InitLimits,
+			c.InitCPURequest, c.InitMemRequest, c.InitCPULimit, c.InitMemLimit},
+		{k8s.SidecarContainers, c.SidecarContainers, c.SidecarRequests, c.SidecarLimits,
+			c.SidecarCPURequest, c.SidecarMemRequest, c.SidecarCPULimit, c.SidecarMemLimit},
+	} {
+		requests, limits, err := resourceLists(group.requests, group.limits, group.cpuRequest, group.memRequest, group.cpuLimit, group.memLimit)
+		if err != nil {
+			return k8s.ResourceConfig{}, err
+		}
+		addContainers(&resCfg, group.typ, group.selectors, requests, limits)
+	}
+	return resCfg, resCfg.Validate()
+}
+
+// resourceLists parses the requests and limits lists and applies the CPU
+// and memory values on top of them. Empty values are left out so that the
+// resource is left unchanged.
+func resourceLists(requests, limits, cpuRequest, memRequest, cpuLimit, memLimit string) (corev1.ResourceList, corev1.ResourceList, error) {
+	reqList, err := k8s.ParseResourceList(requests)
+	if err != nil {
+		return nil, nil, err
+	}
+	limList, err := k8s.ParseResourceList(limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range []struct {
+		list  *corev1.ResourceList
+		name  corev1.ResourceName
+		value string
+	}{
+		{&reqList, corev1.ResourceCPU, cpuRequest},
+		{&reqList, corev1.ResourceMemory, memRequest},
+		{&limList, corev1.ResourceCPU, cpuLimit},
+		{&limList, corev1.ResourceMemory, memLimit},
+	} {
+		if v.value == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(v.value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid quantity %q for %s: %w", v.value, v.name, err)
+		}
+		if *v.list == nil {
+			*v.list = corev1.ResourceList{}
+		}
+		(*v.list)[v.name] = q
+	}
+	return reqList, limList, nil
+}
+
+// addContainers appends a container config per selector to resCfg. Resources
+// missing from requests and limits fall back to the top-level values of
+// resCfg.
+func addContainers(resCfg *k8s.ResourceConfig, typ k8s.ContainerType, selectors []string, requests, limits corev1.ResourceList) {
+	merge := func(fallback, list corev1.ResourceList) corev1.ResourceList {
+		merged := fallback.DeepCopy()
+		for name, q := range list {
+			if merged == nil {
+				merged = corev1.ResourceList{}
+			}
+			merged[name] = q
+		}
+		return merged
+	}
+	for _, selector := range selectors {
+		resCfg.Containers = append(resCfg.Containers, k8s.ContainerConfig{
+			Selector:    selector,
+			Type:        typ,
+			Requests:    merge(resCfg.Requests, requests),
+			Limits:      merge(resCfg.Limits, limits),
+			Remove:      resCfg.Remove,
+			Adjustments: resCfg.Adjustments,
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s-resource-adjustment/internal/config"
+	"k8s-resource-adjustment/internal/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestConfig_ValidateRepositoryResources(t *testing.T) {
+	repo := config.Resources{
+		CPURequest: "500m",
+		Containers: map[string]config.Resources{"worker": {MemLimit: "1Gi"}},
+	}
+	requests := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}
+	worker := k8s.ContainerConfig{
+		Selector: "worker",
+		Type:     k8s.AppContainers,
+		Requests: requests,
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+
+	tests := []struct {
+		name       string
+		containers []string
+		want       []k8s.ContainerConfig
+	}{
+		{
+			name: "container entry is an override next to the first container",
+			want: []k8s.ContainerConfig{withOverride(worker)},
+		},
+		{
+			name:       "container replaces the CONTAINERS entry",
+			containers: []string{"worker", "cron"},
+			want: []k8s.ContainerConfig{
+				worker,
+				{Selector: "cron", Type: k8s.AppContainers, Requests: requests},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.CPURequest = "100m"
+			cfg.Containers = tt.containers
+			cfg.Repositories = map[string]config.Resources{"api": repo}
+			parsed, err := cfg.Validate()
+			if err != nil {
+				t.Fatalf("Validate() unexpected error = %v", err)
+			}
+			got := parsed.RepositoryResources["api"]
+			want := k8s.ResourceConfig{Requests: requests, Containers: tt.want}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Validate() RepositoryResources[api] = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// withOverride returns c marked as an override.
+func withOverride(c k8s.ContainerConfig) k8s.ContainerConfig {
+	c.Override = true
+	return c
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// placeholder matches template values such as "__GIT_URL__" left in a
+// configuration.
+var placeholder = regexp.MustCompile(`__[A-Z0-9_]+__`)
+
+// scpLike matches scp-like remotes such as "git@gitlab.com:group".
+var scpLike = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// Parsed holds the values of a Config that are parsed from their text form,
+// as returned by Config.Validate.
+type Parsed struct {
+	// CustomKinds are the kinds registered by CUSTOM_KINDS.
+	CustomKinds []k8s.CustomKind
+	// Resources are applied to the repositories without resource values of
+	// their own, and RepositoryResources, keyed by repository, to the others.
+	Resources           k8s.ResourceConfig
+	RepositoryResources map[string]k8s.ResourceConfig
+	// MinResources, MaxResources and MaxLimitRequestRatio are the guardrail
+	// rules.
+	MinResources         corev1.ResourceList
+	MaxResources         corev1.ResourceList
+	MaxLimitRequestRatio corev1.ResourceList
+	// FailureLimit is the number of repositories that may fail before the run
+	// is stopped, or -1 for no limit.
+	FailureLimit int
+}
+
+// Validate checks the values of c that can be checked without contacting
+// the remotes and returns them parsed, along with every problem found,
+// joined with errors.Join.
+func (c Config) Validate() (Parsed, error) {
+	var parsed Parsed
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	for _, v := range []struct{ key, value string }{
+		{"ENV", c.Env},
+		{"BASE_URL", c.BaseURL},
+		{"BRANCH", c.Branch},
+	} {
+		if v.value == "" {
+			check(v.key, errors.New("required"))
+		}
+	}
+	if len(c.RepoURLs) == 0 {
+		check("REPO_URLS", errors.New("required"))
+	}
+	for _, v := range []struct{ key, value string }{
+		{"ENV", c.Env},
+		{"BASE_URL", c.BaseURL},
+		{"BRANCH", c.Branch},
+		{"REPO_URLS", strings.Join(c.RepoURLs, ",")},
+		{"GIT_TOKEN", c.GitToken},
+		{"GITLAB_TOKEN", c.GitLabToken},
+		{"GITHUB_TOKEN", c.GitHubToken},
+	} {
+		if p := placeholder.FindString(v.value); p != "" {
+			check(v.key, fmt.Errorf("placeholder %s must be replaced", p))
+		}
+	}
+	for _, repo := range c.RepoURLs {
+		if repo == "" || strings.ContainsAny(repo, " \t\n") {
+			check("REPO_URLS", fmt.Errorf("invalid repository %q", repo))
+		}
+	}
+
+	if c.BaseURL != "" {
+		check("BASE_URL", validRemote(c.BaseURL))
+	}
+	check("GITLAB_BASE_URL", validHTTPURL(c.GitLabBaseURL))
+	check("GITHUB_BASE_URL", validHTTPURL(c.GitHubBaseURL))
+	if c.Branch != "" {
+		check("BRANCH", validBranch(c.Branch))
+	}
+	if c.MRTargetBranch != "" {
+		check("MR_TARGET_BRANCH", validBranch(c.MRTargetBranch))
+	}
+	if c.MergeRequest {
+		check("MR_BRANCH_PREFIX", validBranch(c.MRBranchPrefix+"run"))
+	}
+
+	before := len(errs)
+	for _, v := range []struct{ key, value string }{
+		{"CPU_LIMIT", c.CPULimit},
+		{"MEM_LIMIT", c.MemLimit},
+		{"CPU_REQUEST", c.CPURequest},
+		{"MEM_REQUEST", c.MemRequest},
+		{"INIT_CPU_LIMIT", c.InitCPULimit},
+		{"INIT_MEM_LIMIT", c.InitMemLimit},
+		{"INIT_CPU_REQUEST", c.InitCPURequest},
+		{"INIT_MEM_REQUEST", c.InitMemRequest},
+		{"SIDECAR_CPU_LIMIT", c.SidecarCPULimit},
+		{"SIDECAR_MEM_LIMIT", c.SidecarMemLimit},
+		{"SIDECAR_CPU_REQUEST", c.SidecarCPURequest},
+		{"SIDECAR_MEM_REQUEST", c.SidecarMemRequest},
+	} {
+		if v.value != "" {
+			if _, err := resource.ParseQuantity(v.value); err != nil {
+				check(v.key, fmt.Errorf("invalid quantity %q: %w", v.value, err))
+			}
+		}
+	}
+	for _, v := range []struct{ key, value string }{
+		{"REQUESTS", c.Requests},
+		{"LIMITS", c.Limits},
+		{"INIT_REQUESTS", c.InitRequests},
+		{"INIT_LIMITS", c.InitLimits},
+		{"SIDECAR_REQUESTS", c.SidecarRequests},
+		{"SIDECAR_LIMITS", c.SidecarLimits},
+		{"ROUNDING", c.Rounding},
+	} {
+		_, err := k8s.ParseResourceList(v.value)
+		check(v.key, err)
+	}
+	for _, v := range []struct {
+		key, value string
+		list       *corev1.ResourceList
+	}{
+		{"MIN_RESOURCES", c.MinResources, &parsed.MinResources},
+		{"MAX_RESOURCES", c.MaxResources, &parsed.MaxResources},
+		{"MAX_LIMIT_REQUEST_RATIO", c.MaxLimitRequestRatio, &parsed.MaxLimitRequestRatio},
+	} {
+		var err error
+		*v.list, err = k8s.ParseResourceList(v.value)
+		check(v.key, err)
+	}
+	_, err := k8s.ParseAdjustments(c.Adjustments)
+	check("ADJUSTMENTS", err)
+	for _, name := range slices.Sorted(maps.Keys(c.Repositories)) {
+		r := c.Repositories[name]
+		r.validate("repositories."+name, check)
+		for _, selector := range slices.Sorted(maps.Keys(r.Containers)) {
+			container := r.Containers[selector]
+			scope := "repositories." + name + ".containers." + selector
+			if len(container.Containers) > 0 {
+				check(scope, errors.New("containers are not supported in a container"))
+			}
+			container.validate(scope, check)
+		}
+	}
+	// The selectors and the pairing of extended resources are checked on the
+	// resulting configurations, once their values parse.
+	if len(errs) == before {
+		parsed.Resources, err = c.resourceConfig(nil)
+		check("resources", err)
+		parsed.RepositoryResources = map[string]k8s.ResourceConfig{}
+		for _, name := range slices.Sorted(maps.Keys(c.Repositories)) {
+			parsed.RepositoryResources[name], err = c.repositoryResourceConfig(name)
+			check("repositories."+name, err)
+		}
+	}
+	parsed.CustomKinds, err = k8s.ParseCustomKinds(c.CustomKinds)
+	check("CUSTOM_KINDS", err)
+
+	if c.Provider != "gitlab" && c.Provider != "github" {
+		check("PROVIDER", fmt.Errorf("unknown provider %q, expected gitlab or github", c.Provider))
+	}
+	if c.SigningFormat != "openpgp" && c.SigningFormat != "ssh" {
+		check("SIGNING_FORMAT", fmt.Errorf("unknown format %q, expected openpgp or ssh", c.SigningFormat))
+	}
+
+	if c.Workers < 1 {
+		check("WORKERS", fmt.Errorf("must be at least 1, got %d", c.Workers))
+	}
+	if c.PushAttempts < 1 {
+		check("PUSH_ATTEMPTS", fmt.Errorf("must be at least 1, got %d", c.PushAttempts))
+	}
+	for _, v := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"PUSH_BACKOFF", c.PushBackoff},
+		{"TIMEOUT", c.Timeout},
+		{"REPO_TIMEOUT", c.RepoTimeout},
+	} {
+		if v.value < 0 {
+			check(v.key, fmt.Errorf("must not be negative, got %v", v.value))
+		}
+	}
+	parsed.FailureLimit, err = FailureLimit(c.FailureThreshold, len(c.RepoURLs))
+	check("FAILURE_THRESHOLD", err)
+
+	return parsed, errors.Join(errs...)
+}
+
+// validate checks the values of r, reporting problems under scope.
+func (r Resources) validate(scope string, check func(key string, err error)) {
+	for _, v := range []struct{ key, value string }{
+		{"cpu_limit", r.CPULimit},
+		{"mem_limit", r.MemLimit},
+		{"cpu_request", r.CPURequest},
+		{"mem_request", r.MemRequest},
+	} {
+		if v.value != "" {
+			if _, err := resource.ParseQuantity(v.value); err != nil {
+				check(scope+"."+v.key, fmt.Errorf("invalid quantity %q: %w", v.value, err))
+			}
+		}
+	}
+	_, err := k8s.ParseResourceList(r.Requests)
+	check(scope+".requests", err)
+	_, err = k8s.ParseResourceList(r.Limits)
+	check(scope+".limits", err)
+	_, err = k8s.ParseAdjustments(r.Adjustments)
+	check(scope+".adjustments", err)
+}
+
+// FailureLimit returns the number of repositories out of total that may
+// fail before the run is stopped, for a threshold that is either a number
+// of repositories or a percentage such as "10%". An empty threshold returns
+// -1, meaning no limit.
+func FailureLimit(threshold string, total int) (int, error) {
+	if threshold == "" {
+		return -1, nil
+	}
+	if percent, ok := strings.CutSuffix(threshold, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", threshold)
+		}
+		return int(p * float64(total) / 100), nil
+	}
+	n, err := strconv.Atoi(threshold)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number of repositories %q", threshold)
+	}
+	return n, nil
+}
+
+// validRemote checks that s is a URL with a host, a file URL or an
+// scp-like address.
+func validRemote(s string) error {
+	if scpLike.MatchString(s) {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" && u.Scheme != "file" {
+		return fmt.Errorf("invalid URL %q", s)
+	}
+	return nil
+}
+
+// validHTTPURL checks that s is an http or https URL with a host.
+func validHTTPURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", s)
+	}
+	return nil
+}
+
+// validBranch checks that name, with or without its refs/heads/ prefix, is
+// a valid branch name.
+func validBranch(name string) error {
+	ref := plumbing.NewBranchReferenceName(strings.TrimPrefix(name, "refs/heads/"))
+	if err := ref.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package config_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-resource-adjustment/internal/config"
+	"k8s-resource-adjustment/internal/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFailureLimit(t *testing.T) {
+	tests := []struct {
+		threshold string
+		total     int
+		want      int
+		wantErr   bool
+	}{
+		{threshold: "", total: 10, want: -1},
+		{threshold: "0", total: 10, want: 0},
+		{threshold: "3", total: 10, want: 3},
+		{threshold: "25", total: 10, want: 25},
+		{threshold: "10%", total: 10, want: 1},
+		{threshold: "10%", total: 25, want: 2},
+		{threshold: "10%", total: 5, want: 0},
+		{threshold: "12.5%", total: 80, want: 10},
+		{threshold: "100%", total: 7, want: 7},
+		{threshold: "0%", total: 7, want: 0},
+		{threshold: "-1", total: 10, wantErr: true},
+		{threshold: "many", total: 10, wantErr: true},
+		{threshold: "150%", total: 10, wantErr: true},
+		{threshold: "-5%", total: 10, wantErr: true},
+		{threshold: "%", total: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.threshold, func(t *testing.T) {
+			got, err := config.FailureLimit(tt.threshold, tt.total)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FailureLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("FailureLimit(%q, %d) = %d, want %d", tt.threshold, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+// validConfig returns a configuration that passes Validate.
+func validConfig() config.Config {
+	return config.Config{
+		Env:           "dev",
+		BaseURL:       "https://gitlab.com/group",
+		Branch:        "refs/heads/main",
+		RepoURLs:      []string{"api"},
+		Provider:      "gitlab",
+		SigningFormat: "openpgp",
+		GitLabBaseURL: "https://gitlab.com",
+		GitHubBaseURL: "https://api.github.com",
+		Workers:       1,
+		PushAttempts:  1,
+	}
+}
+
+func TestConfig_ValidateParsed(t *testing.T) {
+	c := validConfig()
+	c.RepoURLs = []string{"api", "web", "jobs", "batch"}
+	c.CPURequest = "100m"
+	c.CustomKinds = "serving.knative.dev/v1/Service=spec.template.spec.containers"
+	c.MinResources = "cpu=10m"
+	c.MaxLimitRequestRatio = "cpu=4"
+	c.FailureThreshold = "50%"
+
+	got, err := c.Validate()
+	if err != nil {
+		t.Fatalf("Validate() unexpected error = %v", err)
+	}
+	want, _ := k8s.ParseCustomKinds(c.CustomKinds)
+	if !reflect.DeepEqual(got.CustomKinds, want) {
+		t.Errorf("Validate() CustomKinds = %+v, want %+v", got.CustomKinds, want)
+	}
+	if q := got.Resources.Requests[corev1.ResourceCPU]; q.String() != "100m" {
+		t.Errorf("Validate() Resources.Requests = %v, want cpu=100m", got.Resources.Requests)
+	}
+	if q := got.MinResources[corev1.ResourceCPU]; q.String() != "10m" || got.MaxResources != nil {
+		t.Errorf("Validate() MinResources = %v, MaxResources = %v, want cpu=10m and none", got.MinResources, got.MaxResources)
+	}
+	if q := got.MaxLimitRequestRatio[corev1.ResourceCPU]; q.String() != "4" {
+		t.Errorf("Validate() MaxLimitRequestRatio = %v, want cpu=4", got.MaxLimitRequestRatio)
+	}
+	if got.FailureLimit != 2 {
+		t.Errorf("Validate() FailureLimit = %d, want 2", got.FailureLimit)
+	}
+}
+
+func TestConfig_ValidateResources(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(c *config.Config)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *config.Config) {},
+		},
+		{
+			name: "invalid selector with other problems",
+			modify: func(c *config.Config) {
+				c.Containers = []string{"/(/"}
+				c.Provider = "bitbucket"
+			},
+			want: []string{"resources: invalid container selector \"/(/\"", "PROVIDER: unknown provider"},
+		},
+		{
+			name:   "extended resource without a limit",
+			modify: func(c *config.Config) { c.Requests = "nvidia.com/gpu=1" },
+			want:   []string{"resources: ", "nvidia.com/gpu"},
+		},
+		{
+			name: "invalid selector of a repository",
+			modify: func(c *config.Config) {
+				c.Repositories = map[string]config.Resources{
+					"api": {Containers: map[string]config.Resources{"[": {CPURequest: "100m"}}},
+				}
+			},
+			want: []string{"repositories.api: invalid container selector \"[\""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			_, err := c.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() expected an error, but got nil")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
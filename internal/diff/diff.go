@@ -0,0 +1,36 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// Unified returns a unified diff of a file at path before and after a change,
+// or an empty string if both are equal.
+func Unified(path string, before, after []byte) (string, error) {
+	if string(before) == string(after) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  contextLines,
+	})
+}
+
+// splitLines splits data into lines that each end with a newline. Unlike
+// difflib.SplitLines, a trailing newline does not yield an extra empty line.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,32 @@
+package diff_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/diff"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	before := []byte("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      limits:\n        cpu: 100m\n        memory: 128Mi\n")
+	after := []byte("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      limits:\n        cpu: 200m\n        memory: 128Mi\n")
+
+	got, err := diff.Unified("overlays/dev/patches/set_resources.yaml", before, after)
+	require.NoError(t, err)
+	assert.Equal(t, `--- a/overlays/dev/patches/set_resources.yaml
++++ b/overlays/dev/patches/set_resources.yaml
+@@ -4,5 +4,5 @@
+   - name: app
+     resources:
+       limits:
+-        cpu: 100m
++        cpu: 200m
+         memory: 128Mi
+`, got)
+
+	got, err = diff.Unified("set_resources.yaml", before, before)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,137 @@
+package gitops
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v6/plumbing/transport/ssh"
+	"github.com/jdxcode/netrc"
+)
+
+// ErrNoNetrcEntry is returned by NetrcCredentials when the .netrc file has
+// no entry for the host.
+var ErrNoNetrcEntry = errors.New("no .netrc entry")
+
+// Auth holds the credentials used to clone and push. They are picked by the
+// remote URL: SSH URLs use the private key at SSHKeyPath, or the SSH agent
+// when it is unset, and HTTP(S) URLs use Token, then Username and Password,
+// then the .netrc entry of the host. Other URLs, such as file://, use none.
+type Auth struct {
+	// Token is sent as the password of HTTP basic auth, which GitLab and
+	// GitHub accept for access tokens. Username defaults to "oauth2".
+	Token    string
+	Username string
+	Password string
+	// NetrcPath is the .netrc file; empty means $NETRC or $HOME/.netrc.
+	NetrcPath string
+	// SSHKeyPath is a PEM or OpenSSH private key, decrypted with
+	// SSHKeyPassphrase when encrypted.
+	SSHKeyPath       string
+	SSHKeyPassphrase string
+	// KnownHostsPath is the known_hosts file SSH servers are verified
+	// against; empty means $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+	KnownHostsPath string
+}
+
+// Method returns the auth method for the remote rawURL, or nil when no
+// credentials apply.
+func (a *Auth) Method(rawURL string) (transport.AuthMethod, error) {
+	scheme, user, host := parseRemote(rawURL)
+	switch scheme {
+	case "ssh":
+		if a.SSHKeyPath == "" {
+			return nil, nil
+		}
+		if user == "" {
+			user = "git"
+		}
+		keys, err := gitssh.NewPublicKeysFromFile(user, a.SSHKeyPath, a.SSHKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key %s: %w", a.SSHKeyPath, err)
+		}
+		var knownHosts []string
+		if a.KnownHostsPath != "" {
+			knownHosts = append(knownHosts, a.KnownHostsPath)
+		}
+		keys.HostKeyCallback, err = gitssh.NewKnownHostsCallback(knownHosts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+		return keys, nil
+	case "http", "https":
+		if a.Token != "" {
+			username := a.Username
+			if username == "" {
+				username = "oauth2"
+			}
+			return &githttp.BasicAuth{Username: username, Password: a.Token}, nil
+		}
+		if a.Username != "" || a.Password != "" {
+			return &githttp.BasicAuth{Username: a.Username, Password: a.Password}, nil
+		}
+		login, password, err := NetrcCredentials(a.NetrcPath, host)
+		if errors.Is(err, ErrNoNetrcEntry) || errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &githttp.BasicAuth{Username: login, Password: password}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// NetrcCredentials returns the login and password of the entry for host in
+// the .netrc file at path, which defaults to $NETRC or $HOME/.netrc.
+func NetrcCredentials(path, host string) (login, password string, err error) {
+	if path == "" {
+		path = DefaultNetrcPath()
+	}
+	n, err := netrc.Parse(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", fmt.Errorf(".netrc file not found at %s: %w", path, os.ErrNotExist)
+		}
+		return "", "", fmt.Errorf("error parsing .netrc file: %w", err)
+	}
+	machine := n.Machine(host)
+	if machine == nil {
+		return "", "", fmt.Errorf("%w for %s in %s", ErrNoNetrcEntry, host, path)
+	}
+	return machine.Get("login"), machine.Get("password"), nil
+}
+
+// DefaultNetrcPath returns $NETRC or, if it is unset, $HOME/.netrc.
+func DefaultNetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".netrc")
+}
+
+// parseRemote returns the scheme, user and host of a remote URL. SCP-like
+// addresses such as "git@gitlab.com:group/service.git" have the scheme "ssh".
+func parseRemote(rawURL string) (scheme, user, host string) {
+	if !strings.Contains(rawURL, "://") {
+		userHost, _, ok := strings.Cut(rawURL, ":")
+		if !ok || strings.Contains(userHost, "/") {
+			return "file", "", ""
+		}
+		if u, h, ok := strings.Cut(userHost, "@"); ok {
+			return "ssh", u, h
+		}
+		return "ssh", "", userHost
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", ""
+	}
+	return u.Scheme, u.User.Username(), u.Hostname()
+}
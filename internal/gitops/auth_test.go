@@ -0,0 +1,151 @@
+package gitops_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-resource-adjustment/internal/gitops"
+
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v6/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuth_Method_HTTP(t *testing.T) {
+	netrcPath := writeFile(t, ".netrc", "machine gitlab.example.com\n  login robot\n  password from-netrc\n")
+
+	tests := []struct {
+		name string
+		auth gitops.Auth
+		url  string
+		want *githttp.BasicAuth
+	}{
+		{"token", gitops.Auth{Token: "secret", NetrcPath: netrcPath}, "https://gitlab.example.com/group/service.git",
+			&githttp.BasicAuth{Username: "oauth2", Password: "secret"}},
+		{"token with username", gitops.Auth{Token: "secret", Username: "x-access-token"}, "https://github.com/org/service.git",
+			&githttp.BasicAuth{Username: "x-access-token", Password: "secret"}},
+		{"basic auth", gitops.Auth{Username: "alice", Password: "pw", NetrcPath: netrcPath}, "https://gitlab.example.com/group/service.git",
+			&githttp.BasicAuth{Username: "alice", Password: "pw"}},
+		{"netrc", gitops.Auth{NetrcPath: netrcPath}, "https://gitlab.example.com/group/service.git",
+			&githttp.BasicAuth{Username: "robot", Password: "from-netrc"}},
+		{"no netrc entry", gitops.Auth{NetrcPath: netrcPath}, "https://github.com/org/service.git", nil},
+		{"no netrc file", gitops.Auth{NetrcPath: filepath.Join(t.TempDir(), ".netrc")}, "https://github.com/org/service.git", nil},
+		{"local repository", gitops.Auth{Token: "secret"}, "file:///tmp/repo", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.Method(tt.url)
+			if err != nil {
+				t.Fatalf("Method() unexpected error = %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Method() got %v, want nil", got)
+				}
+				return
+			}
+			basic, ok := got.(*githttp.BasicAuth)
+			if !ok || *basic != *tt.want {
+				t.Errorf("Method() got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_Method_SSH(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := writeFile(t, "id_ed25519", string(pem.EncodeToMemory(block)))
+
+	hostPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewPublicKey(hostPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHostsPath := writeFile(t, "known_hosts", knownhosts.Line([]string{"gitlab.example.com"}, hostKey)+"\n")
+
+	auth := gitops.Auth{SSHKeyPath: keyPath, SSHKeyPassphrase: "passphrase", KnownHostsPath: knownHostsPath}
+
+	t.Run("scp-like URL", func(t *testing.T) {
+		got, err := auth.Method("git@gitlab.example.com:group/service.git")
+		if err != nil {
+			t.Fatalf("Method() unexpected error = %v", err)
+		}
+		keys, ok := got.(*gitssh.PublicKeys)
+		if !ok {
+			t.Fatalf("Method() got %T, want *ssh.PublicKeys", got)
+		}
+		if keys.User != "git" {
+			t.Errorf("Method() user = %q, want %q", keys.User, "git")
+		}
+
+		addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+		if err := keys.HostKeyCallback("gitlab.example.com:22", addr, hostKey); err != nil {
+			t.Errorf("HostKeyCallback() rejected the known host key: %v", err)
+		}
+		otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+		otherKey, _ := ssh.NewPublicKey(otherPub)
+		if err := keys.HostKeyCallback("gitlab.example.com:22", addr, otherKey); err == nil {
+			t.Error("HostKeyCallback() accepted an unknown host key")
+		}
+	})
+
+	t.Run("ssh URL with user", func(t *testing.T) {
+		got, err := auth.Method("ssh://deploy@gitlab.example.com/group/service.git")
+		if err != nil {
+			t.Fatalf("Method() unexpected error = %v", err)
+		}
+		if keys, ok := got.(*gitssh.PublicKeys); !ok || keys.User != "deploy" {
+			t.Errorf("Method() got %#v, want public keys for user deploy", got)
+		}
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		wrong := auth
+		wrong.SSHKeyPassphrase = "wrong"
+		if _, err := wrong.Method("git@gitlab.example.com:group/service.git"); err == nil {
+			t.Error("Method() expected an error for a wrong passphrase, but got nil")
+		}
+	})
+}
+
+func TestNetrcCredentials(t *testing.T) {
+	path := writeFile(t, ".netrc", "machine github.com login octocat password token\n")
+
+	login, password, err := gitops.NetrcCredentials(path, "github.com")
+	if err != nil {
+		t.Fatalf("NetrcCredentials() unexpected error = %v", err)
+	}
+	if login != "octocat" || password != "token" {
+		t.Errorf("NetrcCredentials() got %q, %q, want %q, %q", login, password, "octocat", "token")
+	}
+
+	_, _, err = gitops.NetrcCredentials(path, "gitlab.com")
+	if !errors.Is(err, gitops.ErrNoNetrcEntry) {
+		t.Errorf("NetrcCredentials() error = %v, want %v", err, gitops.ErrNoNetrcEntry)
+	}
+}
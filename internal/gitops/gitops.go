@@ -1,29 +1,74 @@
 package gitops
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-git/go-billy/v6/memfs"
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/transport"
 	"github.com/go-git/go-git/v6/storage/memory"
 )
 
 // GitRepoManager abstracts git operations
 type GitRepoManager interface {
-	CloneAndWorktree(url, branch string) (*git.Worktree, *git.Repository, error)
-	CommitAndPush(repo *git.Repository, worktree *git.Worktree, filePath string) error
-	GetFile(worktree *git.Worktree, path string) ([]byte, error)
+	CloneAndWorktree(ctx context.Context, url, branch string) (*git.Worktree, *git.Repository, error)
+	CommitAndPush(ctx context.Context, repo *git.Repository, worktree *git.Worktree, filePath, message string) error
+	// CommitAndPushBranch commits filePath and pushes the commit to a new
+	// branch instead of the cloned one.
+	CommitAndPushBranch(ctx context.Context, repo *git.Repository, worktree *git.Worktree, filePath, branch, message string) error
+	GetFile(ctx context.Context, worktree *git.Worktree, path string) ([]byte, error)
+	// FetchAndReset fetches branch from the remote and resets the worktree
+	// to it, discarding local commits, e.g. after ErrPushRejected.
+	FetchAndReset(ctx context.Context, repo *git.Repository, worktree *git.Worktree, branch string) error
 }
 
-type InMemoryGitRepoManager struct{}
+// ErrNoChanges is returned by CommitAndPush when the file is unchanged, so
+// that no empty commit is created.
+var ErrNoChanges = errors.New("nothing to commit")
 
-func (g *InMemoryGitRepoManager) CloneAndWorktree(url, branch string) (*git.Worktree, *git.Repository, error) {
+// ErrPushRejected is returned by CommitAndPush and CommitAndPushBranch when
+// the remote branch moved since the clone, so that the push is not a
+// fast-forward.
+var ErrPushRejected = errors.New("push rejected")
+
+// Identity is the name and email address of a commit author or committer.
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// DefaultIdentity is the author of commits when none is configured.
+var DefaultIdentity = Identity{Name: "AutoUpdater", Email: "autoupdater@example.com"}
+
+type InMemoryGitRepoManager struct {
+	// Auth holds the credentials used to clone and push; nil means none.
+	Auth *Auth
+	// Author defaults to DefaultIdentity and Committer to Author.
+	Author    Identity
+	Committer Identity
+	// SignKey signs commits with an OpenPGP key and Signer with any other
+	// key, such as an SSHSigner. Signer takes precedence; nil means unsigned.
+	SignKey *openpgp.Entity
+	Signer  git.Signer
+}
+
+func (g *InMemoryGitRepoManager) CloneAndWorktree(ctx context.Context, url, branch string) (*git.Worktree, *git.Repository, error) {
+	auth, err := g.authMethod(url)
+	if err != nil {
+		return nil, nil, err
+	}
 	fs := memfs.New()
-	repo, err := git.Clone(memory.NewStorage(), fs, &git.CloneOptions{
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), fs, &git.CloneOptions{
 		URL:           url,
+		Auth:          auth,
 		SingleBranch:  true,
 		ReferenceName: plumbing.ReferenceName(branch),
 	})
@@ -37,25 +82,168 @@ func (g *InMemoryGitRepoManager) CloneAndWorktree(url, branch string) (*git.Work
 	return worktree, repo, nil
 }
 
-func (g *InMemoryGitRepoManager) CommitAndPush(repo *git.Repository, worktree *git.Worktree, filePath string) error {
-	_, err := worktree.Add(filePath)
+func (g *InMemoryGitRepoManager) CommitAndPush(ctx context.Context, repo *git.Repository, worktree *git.Worktree, filePath, message string) error {
+	if _, err := g.commit(ctx, worktree, filePath, message); err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	auth, err := g.remoteAuthMethod(repo)
+	if err != nil {
+		return err
+	}
+	return push(ctx, repo, head.Name(), auth)
+}
+
+func (g *InMemoryGitRepoManager) CommitAndPushBranch(ctx context.Context, repo *git.Repository, worktree *git.Worktree, filePath, branch, message string) error {
+	hash, err := g.commit(ctx, worktree, filePath, message)
+	if err != nil {
+		return err
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, hash)); err != nil {
+		return err
+	}
+	auth, err := g.remoteAuthMethod(repo)
+	if err != nil {
+		return err
+	}
+	return push(ctx, repo, ref, auth)
+}
+
+// push pushes the local branch ref to the branch of the same name of the
+// remote. A failed push is returned as ErrPushRejected when the remote
+// branch holds a commit that the local one does not, whether go-git or the
+// server refused it.
+func push(ctx context.Context, repo *git.Repository, ref plumbing.ReferenceName, auth transport.AuthMethod) error {
+	err := repo.PushContext(ctx, &git.PushOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(ref + ":" + ref)},
+		Auth:     auth,
+	})
+	if err == nil {
+		return nil
+	}
+	if diverged, checkErr := remoteDiverged(ctx, repo, ref, auth); checkErr == nil && diverged {
+		return fmt.Errorf("%w: %v", ErrPushRejected, err)
+	}
+	return err
+}
+
+// remoteDiverged reports whether the remote branch ref points to a commit
+// that is not contained in the local branch ref.
+func remoteDiverged(ctx context.Context, repo *git.Repository, ref plumbing.ReferenceName, auth transport.AuthMethod) (bool, error) {
+	local, err := repo.Reference(ref, true)
+	if err != nil {
+		return false, err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false, err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return false, err
+	}
+	for _, r := range refs {
+		if r.Name() != ref || r.Hash() == local.Hash() {
+			continue
+		}
+		theirs, err := repo.CommitObject(r.Hash())
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		ours, err := repo.CommitObject(local.Hash())
+		if err != nil {
+			return false, err
+		}
+		contained, err := theirs.IsAncestor(ours)
+		return !contained, err
+	}
+	return false, nil
+}
+
+func (g *InMemoryGitRepoManager) FetchAndReset(ctx context.Context, repo *git.Repository, worktree *git.Worktree, branch string) error {
+	auth, err := g.remoteAuthMethod(repo)
 	if err != nil {
 		return err
 	}
-	_, err = worktree.Commit("Update set_resources.yaml via automation", &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "AutoUpdater",
-			Email: "autoupdater@example.com",
-			When:  time.Now(),
-		},
+	local := plumbing.ReferenceName(branch)
+	remote := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, local.Short())
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec("+" + local + ":" + remote)},
+		Auth:     auth,
 	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	ref, err := repo.Reference(remote, true)
 	if err != nil {
 		return err
 	}
-	return repo.Push(&git.PushOptions{})
+	return worktree.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset})
+}
+
+// authMethod returns the auth method for the remote url.
+func (g *InMemoryGitRepoManager) authMethod(url string) (transport.AuthMethod, error) {
+	if g.Auth == nil {
+		return nil, nil
+	}
+	return g.Auth.Method(url)
+}
+
+// remoteAuthMethod returns the auth method for the origin remote of repo.
+func (g *InMemoryGitRepoManager) remoteAuthMethod(repo *git.Repository) (transport.AuthMethod, error) {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, err
+	}
+	return g.authMethod(remote.Config().URLs[0])
 }
 
-func (g *InMemoryGitRepoManager) GetFile(worktree *git.Worktree, path string) ([]byte, error) {
+// commit stages filePath and commits it with message, failing with
+// ErrNoChanges if the file is unchanged. go-git cannot cancel a commit, so
+// ctx is only checked before it starts.
+func (g *InMemoryGitRepoManager) commit(ctx context.Context, worktree *git.Worktree, filePath, message string) (plumbing.Hash, error) {
+	if err := ctx.Err(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	_, err := worktree.Add(filePath)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if status.IsClean() {
+		return plumbing.ZeroHash, ErrNoChanges
+	}
+	author := g.Author
+	if author == (Identity{}) {
+		author = DefaultIdentity
+	}
+	committer := g.Committer
+	if committer == (Identity{}) {
+		committer = author
+	}
+	now := time.Now()
+	return worktree.Commit(message, &git.CommitOptions{
+		Author:    &object.Signature{Name: author.Name, Email: author.Email, When: now},
+		Committer: &object.Signature{Name: committer.Name, Email: committer.Email, When: now},
+		SignKey:   g.SignKey,
+		Signer:    g.Signer,
+	})
+}
+
+func (g *InMemoryGitRepoManager) GetFile(ctx context.Context, worktree *git.Worktree, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	file, err := worktree.Filesystem.Open(path)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,8 @@
 package gitops_test
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"k8s-resource-adjustment/internal/gitops"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/object"
 )
 
@@ -47,24 +50,43 @@ func setupTestRepo(t *testing.T) string {
 	return dir
 }
 
+// setupBareRepo returns a bare clone of a test repository, which accepts
+// pushes to its checked out branch.
+func setupBareRepo(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "origin.git")
+	if _, err := git.PlainClone(dir, true, &git.CloneOptions{URL: "file://" + setupTestRepo(t)}); err != nil {
+		t.Fatalf("Failed to clone bare repo: %v", err)
+	}
+	return dir
+}
+
 func TestCloneAndWorktree(t *testing.T) {
 	dir := setupTestRepo(t)
 	repoURL := "file://" + dir
 	manager := &gitops.InMemoryGitRepoManager{}
 
 	t.Run("success", func(t *testing.T) {
-		_, _, err := manager.CloneAndWorktree(repoURL, "refs/heads/master")
+		_, _, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
 		if err != nil {
 			t.Errorf("CloneAndWorktree() unexpected error = %v", err)
 		}
 	})
 
 	t.Run("non-existent branch", func(t *testing.T) {
-		_, _, err := manager.CloneAndWorktree(repoURL, "refs/heads/doesnotexist")
+		_, _, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/doesnotexist")
 		if err == nil {
 			t.Error("CloneAndWorktree() expected an error for non-existent branch, but got nil")
 		}
 	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, _, err := manager.CloneAndWorktree(ctx, repoURL, "refs/heads/master")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CloneAndWorktree() error = %v, want %v", err, context.Canceled)
+		}
+	})
 }
 
 func TestGetFile(t *testing.T) {
@@ -72,13 +94,13 @@ func TestGetFile(t *testing.T) {
 	repoURL := "file://" + dir
 	manager := &gitops.InMemoryGitRepoManager{}
 
-	worktree, _, err := manager.CloneAndWorktree(repoURL, "refs/heads/master")
+	worktree, _, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
 	if err != nil {
 		t.Fatalf("CloneAndWorktree() failed: %v", err)
 	}
 
 	t.Run("success", func(t *testing.T) {
-		data, err := manager.GetFile(worktree, "testfile.txt")
+		data, err := manager.GetFile(t.Context(), worktree, "testfile.txt")
 		if err != nil {
 			t.Errorf("GetFile() unexpected error = %v", err)
 		}
@@ -89,7 +111,7 @@ func TestGetFile(t *testing.T) {
 	})
 
 	t.Run("non-existent file", func(t *testing.T) {
-		_, err := manager.GetFile(worktree, "nonexistent.txt")
+		_, err := manager.GetFile(t.Context(), worktree, "nonexistent.txt")
 		if err == nil {
 			t.Error("GetFile() expected an error for non-existent file, but got nil")
 		}
@@ -102,7 +124,7 @@ func TestCommitAndPush(t *testing.T) {
 	manager := &gitops.InMemoryGitRepoManager{}
 
 	t.Run("success", func(t *testing.T) {
-		worktree, repo, err := manager.CloneAndWorktree(repoURL, "refs/heads/master")
+		worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
 		if err != nil {
 			t.Fatalf("CloneAndWorktree() failed: %v", err)
 		}
@@ -119,17 +141,17 @@ func TestCommitAndPush(t *testing.T) {
 		}
 		f.Close()
 
-		err = manager.CommitAndPush(repo, worktree, newFilePath)
+		err = manager.CommitAndPush(t.Context(), repo, worktree, newFilePath, "Add newfile.txt")
 		if err != nil {
 			t.Errorf("CommitAndPush() unexpected error = %v", err)
 		}
 
 		// Verify by cloning again and checking the file
-		worktree2, _, err := manager.CloneAndWorktree(repoURL, "refs/heads/master")
+		worktree2, _, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
 		if err != nil {
 			t.Fatalf("CloneAndWorktree() for verification failed: %v", err)
 		}
-		data, err := manager.GetFile(worktree2, newFilePath)
+		data, err := manager.GetFile(t.Context(), worktree2, newFilePath)
 		if err != nil {
 			t.Fatalf("GetFile() for verification failed: %v", err)
 		}
@@ -138,14 +160,199 @@ func TestCommitAndPush(t *testing.T) {
 		}
 	})
 
+	t.Run("push to new branch", func(t *testing.T) {
+		worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := worktree.Filesystem.Create("testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("updated")); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		err = manager.CommitAndPushBranch(t.Context(), repo, worktree, "testfile.txt", "resource-adjustment/test", "Update testfile.txt")
+		if err != nil {
+			t.Fatalf("CommitAndPushBranch() unexpected error = %v", err)
+		}
+
+		worktree2, _, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/resource-adjustment/test")
+		if err != nil {
+			t.Fatalf("CloneAndWorktree() for verification failed: %v", err)
+		}
+		data, err := manager.GetFile(t.Context(), worktree2, "testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "updated" {
+			t.Errorf("GetFile() on new branch got %q, want %q", data, "updated")
+		}
+	})
+
+	t.Run("message and identity", func(t *testing.T) {
+		manager := &gitops.InMemoryGitRepoManager{
+			Author:    gitops.Identity{Name: "Jane", Email: "jane@example.com"},
+			Committer: gitops.Identity{Name: "Bot", Email: "bot@example.com"},
+		}
+		worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := worktree.Filesystem.Create("testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("signed")); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		message := "Adjust resources\n\nSigned-off-by: Jane <jane@example.com>\n"
+		if err := manager.CommitAndPushBranch(t.Context(), repo, worktree, "testfile.txt", "identity", message); err != nil {
+			t.Fatalf("CommitAndPushBranch() unexpected error = %v", err)
+		}
+
+		origin, err := git.PlainOpen(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := origin.Reference(plumbing.NewBranchReferenceName("identity"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := origin.CommitObject(ref.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Message != message {
+			t.Errorf("commit message = %q, want %q", c.Message, message)
+		}
+		if c.Author.Name != "Jane" || c.Author.Email != "jane@example.com" {
+			t.Errorf("commit author = %s <%s>, want Jane <jane@example.com>", c.Author.Name, c.Author.Email)
+		}
+		if c.Committer.Name != "Bot" || c.Committer.Email != "bot@example.com" {
+			t.Errorf("commit committer = %s <%s>, want Bot <bot@example.com>", c.Committer.Name, c.Committer.Email)
+		}
+	})
+
+	t.Run("unchanged file", func(t *testing.T) {
+		worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = manager.CommitAndPush(t.Context(), repo, worktree, "testfile.txt", "Update testfile.txt")
+		if !errors.Is(err, gitops.ErrNoChanges) {
+			t.Errorf("CommitAndPush() error = %v, want %v", err, gitops.ErrNoChanges)
+		}
+	})
+
 	t.Run("error on adding non-existent file", func(t *testing.T) {
-		worktree, repo, err := manager.CloneAndWorktree(repoURL, "refs/heads/master")
+		worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = manager.CommitAndPush(repo, worktree, "doesnotexist.txt")
+		err = manager.CommitAndPush(t.Context(), repo, worktree, "doesnotexist.txt", "Add doesnotexist.txt")
 		if err == nil {
 			t.Errorf("Expected error when adding non-existent file, but got nil")
 		}
 	})
 }
+
+// writeAndCommit writes content to testfile.txt of a new clone of repoURL and
+// commits and pushes it.
+func writeAndCommit(t *testing.T, manager *gitops.InMemoryGitRepoManager, repoURL, content string) error {
+	worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := worktree.Filesystem.Create("testfile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return manager.CommitAndPush(t.Context(), repo, worktree, "testfile.txt", "Update testfile.txt")
+}
+
+func TestFetchAndReset(t *testing.T) {
+	repoURL := "file://" + setupBareRepo(t)
+	manager := &gitops.InMemoryGitRepoManager{}
+
+	worktree, repo, err := manager.CloneAndWorktree(t.Context(), repoURL, "refs/heads/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else pushes between our clone and our push.
+	if err := writeAndCommit(t, manager, repoURL, "theirs"); err != nil {
+		t.Fatalf("CommitAndPush() unexpected error = %v", err)
+	}
+
+	f, err := worktree.Filesystem.Create("testfile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("ours")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	err = manager.CommitAndPush(t.Context(), repo, worktree, "testfile.txt", "Update testfile.txt")
+	if !errors.Is(err, gitops.ErrPushRejected) {
+		t.Fatalf("CommitAndPush() error = %v, want %v", err, gitops.ErrPushRejected)
+	}
+
+	if err := manager.FetchAndReset(t.Context(), repo, worktree, "refs/heads/master"); err != nil {
+		t.Fatalf("FetchAndReset() unexpected error = %v", err)
+	}
+	data, err := manager.GetFile(t.Context(), worktree, "testfile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "theirs" {
+		t.Errorf("GetFile() after FetchAndReset() got %q, want %q", data, "theirs")
+	}
+
+	f, err = worktree.Filesystem.Create("testfile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("theirs and ours")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := manager.CommitAndPush(t.Context(), repo, worktree, "testfile.txt", "Update testfile.txt"); err != nil {
+		t.Errorf("CommitAndPush() after FetchAndReset() unexpected error = %v", err)
+	}
+}
+
+func TestCommitAndPush_OtherErrorsAreNotRejections(t *testing.T) {
+	dir := setupBareRepo(t)
+	manager := &gitops.InMemoryGitRepoManager{}
+	worktree, repo, err := manager.CloneAndWorktree(t.Context(), "file://"+dir, "refs/heads/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote disappears between our clone and our push.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := worktree.Filesystem.Create("testfile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("ours")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	err = manager.CommitAndPush(t.Context(), repo, worktree, "testfile.txt", "Update testfile.txt")
+	if err == nil || errors.Is(err, gitops.ErrPushRejected) {
+		t.Errorf("CommitAndPush() error = %v, want an error other than %v", err, gitops.ErrPushRejected)
+	}
+}
@@ -0,0 +1,121 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHSignatureNamespace is the namespace of SSH commit signatures, as used
+// by git.
+const SSHSignatureNamespace = "git"
+
+// LoadOpenPGPKey returns the first entity of an armored OpenPGP private key,
+// decrypted with passphrase when encrypted, for use as
+// InMemoryGitRepoManager.SignKey.
+func LoadOpenPGPKey(armored []byte, passphrase string) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenPGP key: %w", err)
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, errors.New("OpenPGP key has no private key")
+	}
+	if entity.PrivateKey.Encrypted {
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt OpenPGP key: %w", err)
+		}
+	}
+	return entity, nil
+}
+
+// SSHSigner signs commits with an SSH key in the SSHSIG format verified by
+// "git verify-commit" with gpg.format=ssh.
+type SSHSigner struct {
+	signer ssh.Signer
+}
+
+// NewSSHSigner returns a signer for a PEM or OpenSSH private key, decrypted
+// with passphrase when encrypted.
+func NewSSHSigner(key []byte, passphrase string) (*SSHSigner, error) {
+	var signer ssh.Signer
+	var err error
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH signing key: %w", err)
+	}
+	return &SSHSigner{signer: signer}, nil
+}
+
+// PublicKey returns the public key of the signer.
+func (s *SSHSigner) PublicKey() ssh.PublicKey {
+	return s.signer.PublicKey()
+}
+
+// Sign returns the armored SSH signature of message.
+func (s *SSHSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+	signed := sshSignedData(SSHSignatureNamespace, h.Sum(nil))
+
+	var sig *ssh.Signature
+	var err error
+	if as, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SSHSIG rejects SHA-1 RSA signatures.
+		sig, err = as.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign commit: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{1, s.signer.PublicKey().Marshal(), SSHSignatureNamespace, "", "sha512", ssh.Marshal(sig)})
+	return armorSSHSignature(append([]byte("SSHSIG"), blob...)), nil
+}
+
+// sshSignedData returns the data an SSHSIG signature is computed over.
+func sshSignedData(namespace string, hash []byte) []byte {
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, "", "sha512", hash})...)
+}
+
+// armorSSHSignature wraps sig in "SSH SIGNATURE" armor with lines of 70
+// characters, as ssh-keygen does.
+func armorSSHSignature(sig []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(sig)
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		buf.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	buf.WriteString(encoded + "\n")
+	buf.WriteString("-----END SSH SIGNATURE-----\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,95 @@
+package guardrails
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Validator checks a patched manifest before it is written to a repository.
+type Validator interface {
+	Validate(manifest []byte) error
+}
+
+// ResourceLister returns the resources of every container of a manifest.
+type ResourceLister interface {
+	Resources(manifest []byte) ([]k8s.ContainerResources, error)
+}
+
+// ResourceValidator implements Validator with the constraints Kubernetes and
+// LimitRange objects place on container resources. A request must never
+// exceed its limit, and must equal it for extended resources and huge pages;
+// the other rules are optional.
+type ResourceValidator struct {
+	Lister ResourceLister
+	// Min and Max bound every request and limit of the listed resources.
+	Min corev1.ResourceList
+	Max corev1.ResourceList
+	// MaxLimitRequestRatio caps, per resource, the limit divided by the
+	// request, e.g. cpu=4.
+	MaxLimitRequestRatio corev1.ResourceList
+}
+
+// Validate returns an error listing every violation found in manifest.
+func (v *ResourceValidator) Validate(manifest []byte) error {
+	containers, err := v.Lister.Resources(manifest)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, c := range containers {
+		for _, violation := range v.check(c) {
+			errs = append(errs, fmt.Errorf("%s: %s", c, violation))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check returns the violations of a single container.
+func (v *ResourceValidator) check(c k8s.ContainerResources) []string {
+	var violations []string
+	for _, name := range k8s.ResourceNames(c.Requests, c.Limits) {
+		req, hasReq := c.Requests[name]
+		limit, hasLimit := c.Limits[name]
+
+		switch {
+		case k8s.RequiresEqualLimit(name) && hasReq && !hasLimit:
+			violations = append(violations, fmt.Sprintf("requests.%s %s requires an equal limit", name, req.String()))
+		case k8s.RequiresEqualLimit(name) && hasReq && req.Cmp(limit) != 0:
+			violations = append(violations, fmt.Sprintf("requests.%s %s must equal limits.%s %s", name, req.String(), name, limit.String()))
+		case hasReq && hasLimit && req.Cmp(limit) > 0:
+			violations = append(violations, fmt.Sprintf("requests.%s %s exceeds limits.%s %s", name, req.String(), name, limit.String()))
+		}
+		for _, value := range []struct {
+			key      string
+			quantity resource.Quantity
+			ok       bool
+		}{
+			{"requests." + string(name), req, hasReq},
+			{"limits." + string(name), limit, hasLimit},
+		} {
+			if !value.ok {
+				continue
+			}
+			if lower, ok := v.Min[name]; ok && value.quantity.Cmp(lower) < 0 {
+				violations = append(violations, fmt.Sprintf("%s %s is below the minimum of %s", value.key, value.quantity.String(), lower.String()))
+			}
+			if upper, ok := v.Max[name]; ok && value.quantity.Cmp(upper) > 0 {
+				violations = append(violations, fmt.Sprintf("%s %s is above the maximum of %s", value.key, value.quantity.String(), upper.String()))
+			}
+		}
+		if ratio, ok := v.MaxLimitRequestRatio[name]; ok && hasReq && hasLimit && !req.IsZero() {
+			// limit / request > ratio  <=>  limit > ratio * request
+			maxLimit := float64(req.MilliValue()) * ratio.AsApproximateFloat64()
+			if float64(limit.MilliValue()) > maxLimit {
+				violations = append(violations, fmt.Sprintf("limits.%s %s is more than %s times requests.%s %s", name, limit.String(), ratio.String(), name, req.String()))
+			}
+		}
+	}
+	return violations
+}
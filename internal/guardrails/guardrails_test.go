@@ -0,0 +1,139 @@
+package guardrails_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/guardrails"
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func resourceList(s string) corev1.ResourceList {
+	list, err := k8s.ParseResourceList(s)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+func TestResourceValidator_Validate(t *testing.T) {
+	manifest := func(requests, limits string) []byte {
+		return []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests: {` + requests + `}
+          limits: {` + limits + `}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+`)
+	}
+
+	tests := []struct {
+		name      string
+		validator guardrails.ResourceValidator
+		manifest  []byte
+		wantErrs  []string
+	}{
+		{
+			name:     "valid",
+			manifest: manifest("cpu: 100m, memory: 128Mi", "cpu: 200m, memory: 128Mi"),
+		},
+		{
+			name:     "request exceeds limit",
+			manifest: manifest("cpu: 500m", "cpu: 100m"),
+			wantErrs: []string{"Deployment/api container app: requests.cpu 500m exceeds limits.cpu 100m"},
+		},
+		{
+			name: "min and max",
+			validator: guardrails.ResourceValidator{
+				Min: resourceList("cpu=50m"),
+				Max: resourceList("memory=1Gi"),
+			},
+			manifest: manifest("cpu: 10m, memory: 128Mi", "cpu: 100m, memory: 2Gi"),
+			wantErrs: []string{
+				"requests.cpu 10m is below the minimum of 50m",
+				"limits.memory 2Gi is above the maximum of 1Gi",
+			},
+		},
+		{
+			name:      "limit to request ratio",
+			validator: guardrails.ResourceValidator{MaxLimitRequestRatio: resourceList("cpu=4,memory=1.5")},
+			manifest:  manifest("cpu: 100m, memory: 1Gi", "cpu: 400m, memory: 2Gi"),
+			wantErrs:  []string{"limits.memory 2Gi is more than 1500m times requests.memory 1Gi"},
+		},
+		{
+			name:     "extended resource with an equal limit",
+			manifest: manifest("nvidia.com/gpu: 1, hugepages-2Mi: 64Mi", "nvidia.com/gpu: 1, hugepages-2Mi: 64Mi"),
+		},
+		{
+			name:     "extended resource with a different limit",
+			manifest: manifest("nvidia.com/gpu: 1", "nvidia.com/gpu: 2"),
+			wantErrs: []string{"Deployment/api container app: requests.nvidia.com/gpu 1 must equal limits.nvidia.com/gpu 2"},
+		},
+		{
+			name:     "huge pages without a limit",
+			manifest: manifest("hugepages-2Mi: 64Mi", ""),
+			wantErrs: []string{"requests.hugepages-2Mi 64Mi requires an equal limit"},
+		},
+		{
+			name:     "invalid quantity",
+			manifest: manifest("cpu: lots", "cpu: 1"),
+			wantErrs: []string{`Deployment/api container app: invalid quantity "lots" for requests.cpu`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := tt.validator
+			validator.Lister = &k8s.NodeResourcePatcher{}
+			err := validator.Validate(tt.manifest)
+			if len(tt.wantErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, want := range tt.wantErrs {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestResourceValidator_ValidatePatchedExtendedResources(t *testing.T) {
+	input := []byte(`kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            nvidia.com/gpu: 1
+          limits:
+            nvidia.com/gpu: 1
+`)
+	patcher := &k8s.NodeResourcePatcher{}
+	// The limit is valid on its own but no longer equals the request of the
+	// manifest.
+	resCfg := k8s.ResourceConfig{Limits: resourceList("nvidia.com/gpu=2")}
+	require.NoError(t, resCfg.Validate())
+	patched, err := patcher.Patch(t.Context(), input, resCfg)
+	require.NoError(t, err)
+
+	validator := &guardrails.ResourceValidator{Lister: patcher}
+	assert.EqualError(t, validator.Validate(patched), "Deployment/api container app: requests.nvidia.com/gpu 1 must equal limits.nvidia.com/gpu 2")
+}
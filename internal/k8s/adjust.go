@@ -0,0 +1,267 @@
+package k8s
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Adjustment derives a resource from the current values of the manifest
+// instead of setting an absolute quantity. The new value is
+//
+//	Base × Factor + Offset
+//
+// rounded up to the rounding unit of the resource. Adjustments are applied in
+// order, after the absolute values, and are skipped for containers that have
+// no value for Base.
+type Adjustment struct {
+	// Resource is the entry to change, e.g. "limits.memory".
+	Resource string
+	// Base is the entry the value is derived from, e.g. "requests.cpu".
+	// Empty means Resource itself.
+	Base string
+	// Factor multiplies the base value. Zero means 1.
+	Factor float64
+	// Offset is added to the scaled value and may be negative.
+	Offset resource.Quantity
+}
+
+// ParseAdjustments parses comma-separated adjustments, each in one of the
+// forms
+//
+//	<section>.<name>*<factor>                   limits.memory*1.25
+//	<section>.<name>(+|-)<quantity>             requests.cpu+100m
+//	<section>.<name>=[<factor>*]<base>[(+|-)<quantity>]  limits.cpu=2*requests.cpu
+func ParseAdjustments(s string) ([]Adjustment, error) {
+	var adjustments []Adjustment
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		a, err := parseAdjustment(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid adjustment %q: %w", item, err)
+		}
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, nil
+}
+
+func parseAdjustment(s string) (Adjustment, error) {
+	var a Adjustment
+	target, expr, assign := strings.Cut(s, "=")
+	if assign {
+		a.Resource = strings.TrimSpace(target)
+		if _, _, err := parseResourceKey(a.Resource); err != nil {
+			return a, err
+		}
+	} else {
+		expr = target
+	}
+
+	// A trailing offset is the last + or - whose remainder is a quantity, so
+	// that names such as ephemeral-storage are not split.
+	for i := len(expr) - 1; i > 0; i-- {
+		if (expr[i] != '+' && expr[i] != '-') || strings.HasSuffix(strings.TrimSpace(expr[:i]), "*") {
+			continue
+		}
+		q, err := resource.ParseQuantity(strings.TrimSpace(expr[i+1:]))
+		if err != nil {
+			continue
+		}
+		if expr[i] == '-' {
+			q.Neg()
+		}
+		a.Offset, expr = q, expr[:i]
+		break
+	}
+
+	for _, term := range strings.Split(expr, "*") {
+		term = strings.TrimSpace(term)
+		if f, err := strconv.ParseFloat(term, 64); err == nil {
+			if a.Factor != 0 || f <= 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+				return a, fmt.Errorf("expected a single positive factor")
+			}
+			a.Factor = f
+			continue
+		}
+		if _, _, err := parseResourceKey(term); err != nil {
+			return a, err
+		}
+		if a.Base != "" {
+			return a, fmt.Errorf("expected a single base resource")
+		}
+		a.Base = term
+	}
+	switch {
+	case a.Base == "" && !assign:
+		return a, fmt.Errorf("missing resource")
+	case a.Base == "":
+		return a, fmt.Errorf("missing base resource")
+	case !assign:
+		a.Resource, a.Base = a.Base, ""
+	case a.Base == a.Resource:
+		a.Base = ""
+	}
+	if a.Factor == 0 && a.Offset.IsZero() && a.Base == "" {
+		return a, fmt.Errorf("adjustment has no effect")
+	}
+	return a, nil
+}
+
+// validateAdjustments checks the resource keys of adjustments and that none
+// of them targets a removed entry.
+func validateAdjustments(adjustments []Adjustment, remove []string) error {
+	removed := map[string]bool{}
+	for _, key := range remove {
+		removed[key] = true
+	}
+	for _, a := range adjustments {
+		if _, _, err := parseResourceKey(a.Resource); err != nil {
+			return err
+		}
+		if a.Base != "" {
+			if _, _, err := parseResourceKey(a.Base); err != nil {
+				return err
+			}
+		}
+		if removed[a.Resource] {
+			return fmt.Errorf("resource %s is both adjusted and removed", a.Resource)
+		}
+		if a.Factor < 0 || math.IsInf(a.Factor, 0) || math.IsNaN(a.Factor) {
+			return fmt.Errorf("adjustment of %s has an invalid factor", a.Resource)
+		}
+	}
+	return nil
+}
+
+// defaultRounding returns the unit adjusted values of a resource are rounded
+// up to when no rounding is configured: millicores for CPU, mebibytes for
+// memory, storage and huge pages, and whole units for everything else.
+func defaultRounding(name corev1.ResourceName) resource.Quantity {
+	switch {
+	case name == corev1.ResourceCPU:
+		return resource.MustParse("1m")
+	case name == corev1.ResourceMemory, name == corev1.ResourceEphemeralStorage,
+		strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix):
+		return resource.MustParse("1Mi")
+	default:
+		return resource.MustParse("1")
+	}
+}
+
+// currentResources returns the quantities of the resources block of container,
+// keyed by "<section>.<name>". Entries that are not valid quantities are
+// skipped.
+func currentResources(container *yaml.Node) map[string]resource.Quantity {
+	current := map[string]resource.Quantity{}
+	_, res := mappingEntry(container, "resources")
+	for _, section := range resourceSections {
+		_, sec := mappingEntry(res, section)
+		if sec == nil || sec.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(sec.Content); i += 2 {
+			if q, err := resource.ParseQuantity(sec.Content[i+1].Value); err == nil {
+				current[section+"."+sec.Content[i].Value] = q
+			}
+		}
+	}
+	return current
+}
+
+// adjustValues applies adjustments on top of the current resources of
+// container and values, and returns values extended with the results.
+func adjustValues(container *yaml.Node, values []resourceValue, adjustments []Adjustment, rounding corev1.ResourceList) ([]resourceValue, error) {
+	if len(adjustments) == 0 {
+		return values, nil
+	}
+	current := currentResources(container)
+	for _, v := range values {
+		if v.remove {
+			delete(current, v.section+"."+v.name)
+			continue
+		}
+		current[v.section+"."+v.name] = v.quantity
+	}
+
+	for _, a := range adjustments {
+		base := a.Base
+		if base == "" {
+			base = a.Resource
+		}
+		q, ok := current[base]
+		if !ok {
+			continue
+		}
+		section, name, err := parseResourceKey(a.Resource)
+		if err != nil {
+			return nil, err
+		}
+		unit, ok := rounding[corev1.ResourceName(name)]
+		if !ok {
+			unit = defaultRounding(corev1.ResourceName(name))
+		}
+		adjusted, err := adjust(q, a, unit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adjust %s: %w", a.Resource, err)
+		}
+		current[a.Resource] = adjusted
+
+		v := resourceValue{section: section, name: name, quantity: adjusted}
+		replaced := false
+		for i := range values {
+			if values[i].section == section && values[i].name == name {
+				values[i], replaced = v, true
+			}
+		}
+		if !replaced {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// adjust computes base × a.Factor + a.Offset rounded up to a multiple of unit.
+func adjust(base resource.Quantity, a Adjustment, unit resource.Quantity) (resource.Quantity, error) {
+	value := decimalRat(base)
+	if a.Factor != 0 {
+		factor, ok := new(big.Rat).SetString(strconv.FormatFloat(a.Factor, 'g', -1, 64))
+		if !ok {
+			return resource.Quantity{}, fmt.Errorf("invalid factor %v", a.Factor)
+		}
+		value.Mul(value, factor)
+	}
+	value.Add(value, decimalRat(a.Offset))
+	if value.Sign() < 0 {
+		return resource.Quantity{}, fmt.Errorf("result %s is negative", value.FloatString(3))
+	}
+	if unit.Sign() <= 0 {
+		return resource.Quantity{}, fmt.Errorf("rounding unit %s is not positive", unit.String())
+	}
+
+	// Round up to a whole number of units.
+	units := new(big.Rat).Quo(value, decimalRat(unit))
+	n := new(big.Int).Quo(units.Num(), units.Denom())
+	if !units.IsInt() {
+		n.Add(n, big.NewInt(1))
+	}
+	milli := new(big.Int).Mul(n, big.NewInt(unit.MilliValue()))
+	if !milli.IsInt64() {
+		return resource.Quantity{}, fmt.Errorf("result is too large")
+	}
+	return *resource.NewMilliQuantity(milli.Int64(), base.Format), nil
+}
+
+// decimalRat returns q as an exact rational number.
+func decimalRat(q resource.Quantity) *big.Rat {
+	r, _ := new(big.Rat).SetString(q.AsDec().String())
+	return r
+}
@@ -0,0 +1,213 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseAdjustments(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        []k8s.Adjustment
+		errContains string
+	}{
+		{
+			name:  "scale, offset and ratio",
+			input: "limits.memory*1.25, requests.cpu+100m,limits.ephemeral-storage-1Gi,limits.cpu=2*requests.cpu",
+			want: []k8s.Adjustment{
+				{Resource: "limits.memory", Factor: 1.25},
+				{Resource: "requests.cpu", Offset: resource.MustParse("100m")},
+				{Resource: "limits.ephemeral-storage", Offset: resource.MustParse("-1Gi")},
+				{Resource: "limits.cpu", Base: "requests.cpu", Factor: 2},
+			},
+		},
+		{
+			name:  "ratio with offset",
+			input: "limits.memory=requests.memory*1.5+64Mi",
+			want: []k8s.Adjustment{
+				{Resource: "limits.memory", Base: "requests.memory", Factor: 1.5, Offset: resource.MustParse("64Mi")},
+			},
+		},
+		{
+			name:        "invalid resource",
+			input:       "memory*2",
+			errContains: `invalid adjustment "memory*2": invalid resource key "memory"`,
+		},
+		{
+			name:        "negative factor",
+			input:       "limits.cpu*-1",
+			errContains: "expected a single positive factor",
+		},
+		{
+			name:        "missing base",
+			input:       "limits.cpu=2",
+			errContains: "missing base resource",
+		},
+		{
+			name:        "no effect",
+			input:       "limits.cpu=limits.cpu",
+			errContains: "adjustment has no effect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k8s.ParseAdjustments(tt.input)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.Equal(t, tt.want[i].Resource, got[i].Resource)
+				assert.Equal(t, tt.want[i].Base, got[i].Base)
+				assert.Equal(t, tt.want[i].Factor, got[i].Factor)
+				assert.Zero(t, tt.want[i].Offset.Cmp(got[i].Offset), "offset %s", got[i].Offset.String())
+			}
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchAdjustments(t *testing.T) {
+	input := `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 250m
+            memory: 100Mi
+          limits:
+            memory: 1Gi
+`
+	adjustments := func(s string) []k8s.Adjustment {
+		a, err := k8s.ParseAdjustments(s)
+		require.NoError(t, err)
+		return a
+	}
+
+	tests := []struct {
+		name        string
+		resCfg      k8s.ResourceConfig
+		want        string
+		errContains string
+	}{
+		{
+			name:   "scale and offset",
+			resCfg: k8s.ResourceConfig{Adjustments: adjustments("limits.memory*1.25,requests.cpu+100m")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 350m
+            memory: 100Mi
+          limits:
+            memory: 1280Mi
+`,
+		},
+		{
+			name:   "limit derived from request",
+			resCfg: k8s.ResourceConfig{Adjustments: adjustments("limits.cpu=2*requests.cpu")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 250m
+            memory: 100Mi
+          limits:
+            memory: 1Gi
+            cpu: 500m
+`,
+		},
+		{
+			name: "adjustments apply after absolute values",
+			resCfg: k8s.ResourceConfig{
+				Requests:    resourceList("cpu=1"),
+				Adjustments: adjustments("limits.cpu=requests.cpu*1.5"),
+			},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 1
+            memory: 100Mi
+          limits:
+            memory: 1Gi
+            cpu: 1500m
+`,
+		},
+		{
+			name: "rounding",
+			resCfg: k8s.ResourceConfig{
+				Adjustments: adjustments("requests.cpu*1.1,requests.memory*1.1"),
+				Rounding:    resourceList("cpu=100m,memory=16Mi"),
+			},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 300m
+            memory: 112Mi
+          limits:
+            memory: 1Gi
+`,
+		},
+		{
+			name:   "missing base is skipped",
+			resCfg: k8s.ResourceConfig{Adjustments: adjustments("limits.cpu*2")},
+			want:   input,
+		},
+		{
+			name:        "negative result",
+			resCfg:      k8s.ResourceConfig{Adjustments: adjustments("requests.cpu-1")},
+			errContains: "failed to adjust requests.cpu: result -0.750 is negative",
+		},
+		{
+			name: "adjusted and removed",
+			resCfg: k8s.ResourceConfig{
+				Remove:      []string{"limits.memory"},
+				Adjustments: adjustments("limits.memory*2"),
+			},
+			errContains: "resource limits.memory is both adjusted and removed",
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.Patch(t.Context(), []byte(input), tt.resCfg)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
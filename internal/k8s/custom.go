@@ -0,0 +1,194 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CustomKind registers a kind that is not known to the patcher, typically a
+// CRD, together with the locations of its containers.
+type CustomKind struct {
+	// APIVersion is the group/version of the kind, e.g.
+	// "serving.knative.dev/v1". Empty matches any version.
+	APIVersion string
+	Kind       string
+	// ContainerPaths are JSONPath-like locations of container lists, e.g.
+	// "spec.template.spec.containers" or "spec.workers[*].containers". A
+	// path that resolves to a mapping is treated as a single container
+	// named after its name field or, if absent, the last path segment.
+	// Lists found under an initContainers key are treated as init
+	// containers.
+	ContainerPaths []string
+}
+
+// ParseCustomKinds parses custom kinds in the form
+//
+//	group/version/Kind=path[,path...][;group/version/Kind=path...]
+//
+// The group/version prefix is optional, e.g. "ScaledJob=spec.jobTargetRef.template.spec.containers".
+func ParseCustomKinds(s string) ([]CustomKind, error) {
+	var kinds []CustomKind
+	for _, def := range strings.Split(s, ";") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		gvk, paths, ok := strings.Cut(def, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid custom kind %q: expected <group/version/Kind>=<paths>", def)
+		}
+
+		var ck CustomKind
+		gvk = strings.TrimSpace(gvk)
+		if i := strings.LastIndex(gvk, "/"); i >= 0 {
+			ck.APIVersion, ck.Kind = gvk[:i], gvk[i+1:]
+		} else {
+			ck.Kind = gvk
+		}
+		if ck.Kind == "" {
+			return nil, fmt.Errorf("invalid custom kind %q: missing kind", def)
+		}
+
+		for _, path := range strings.Split(paths, ",") {
+			path = strings.TrimSpace(path)
+			if _, err := parsePath(path); err != nil {
+				return nil, fmt.Errorf("invalid custom kind %q: %w", def, err)
+			}
+			ck.ContainerPaths = append(ck.ContainerPaths, path)
+		}
+		kinds = append(kinds, ck)
+	}
+	return kinds, nil
+}
+
+// pathStep is a single step of a container path: a mapping key or a sequence
+// index, where index -1 selects every item.
+type pathStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parsePath parses a JSONPath-like expression such as
+// "$.spec.workers[*].template.containers" into steps.
+func parsePath(path string) ([]pathStep, error) {
+	p := strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	if p == "" {
+		return nil, fmt.Errorf("empty container path")
+	}
+
+	var steps []pathStep
+	for _, segment := range strings.Split(p, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("invalid container path %q: empty segment", path)
+		}
+		if key != "" {
+			steps = append(steps, pathStep{key: key, isKey: true})
+		}
+		for rest != "" {
+			idx, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid container path %q: missing ]", path)
+			}
+			step := pathStep{index: -1}
+			if idx != "*" {
+				n, err := strconv.Atoi(idx)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid container path %q: bad index %q", path, idx)
+				}
+				step.index = n
+			}
+			steps = append(steps, step)
+			if after != "" && !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("invalid container path %q: unexpected %q", path, after)
+			}
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return steps, nil
+}
+
+// resolvePath returns the nodes reached by following steps from n.
+func resolvePath(n *yaml.Node, steps []pathStep) []*yaml.Node {
+	nodes := []*yaml.Node{n}
+	for _, step := range steps {
+		var next []*yaml.Node
+		for _, cur := range nodes {
+			switch {
+			case step.isKey:
+				if _, v := mappingEntry(cur, step.key); v != nil {
+					next = append(next, v)
+				}
+			case cur.Kind != yaml.SequenceNode:
+			case step.index < 0:
+				next = append(next, cur.Content...)
+			case step.index < len(cur.Content):
+				next = append(next, cur.Content[step.index])
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// lookupCustomKind returns the custom kind registered for apiVersion and kind.
+func (p *NodeResourcePatcher) lookupCustomKind(apiVersion, kind string) (CustomKind, bool) {
+	for _, ck := range p.CustomKinds {
+		if ck.Kind == kind && (ck.APIVersion == "" || ck.APIVersion == apiVersion) {
+			return ck, true
+		}
+	}
+	return CustomKind{}, false
+}
+
+// customContainers returns the containers found at the paths of ck.
+func customContainers(root *yaml.Node, ck CustomKind) ([]*yaml.Node, []containerRef, error) {
+	var nodes []*yaml.Node
+	var refs []containerRef
+	for _, path := range ck.ContainerPaths {
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var last string
+		for _, step := range steps {
+			if step.isKey {
+				last = step.key
+			}
+		}
+
+		for _, found := range resolvePath(root, steps) {
+			items := found.Content
+			if found.Kind == yaml.MappingNode {
+				items = []*yaml.Node{found}
+			} else if found.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, c := range items {
+				name := scalarValue(c, "name")
+				if name == "" && found.Kind == yaml.MappingNode {
+					name = last
+				}
+				nodes = append(nodes, c)
+				refs = append(refs, containerRef{name: name, typ: containerType(last, c)})
+			}
+		}
+	}
+	return nodes, refs, nil
+}
+
+// containerType classifies a container found in the list under key.
+func containerType(key string, container *yaml.Node) ContainerType {
+	if key != "initContainers" {
+		return AppContainers
+	}
+	if scalarValue(container, "restartPolicy") == "Always" {
+		return SidecarContainers
+	}
+	return InitContainers
+}
@@ -0,0 +1,189 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCustomKinds(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        []k8s.CustomKind
+		errContains string
+	}{
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:  "several kinds and paths",
+			input: "serving.knative.dev/v1/Service=spec.template.spec.containers; ScaledJob=spec.jobTargetRef.template.spec.containers, $.spec.jobTargetRef.template.spec.initContainers",
+			want: []k8s.CustomKind{
+				{
+					APIVersion:     "serving.knative.dev/v1",
+					Kind:           "Service",
+					ContainerPaths: []string{"spec.template.spec.containers"},
+				},
+				{
+					Kind: "ScaledJob",
+					ContainerPaths: []string{
+						"spec.jobTargetRef.template.spec.containers",
+						"$.spec.jobTargetRef.template.spec.initContainers",
+					},
+				},
+			},
+		},
+		{
+			name:        "missing paths",
+			input:       "example.com/v1/Widget",
+			errContains: "expected <group/version/Kind>=<paths>",
+		},
+		{
+			name:        "missing kind",
+			input:       "example.com/v1/=spec.containers",
+			errContains: "missing kind",
+		},
+		{
+			name:        "bad index",
+			input:       "Widget=spec.pools[x].containers",
+			errContains: `bad index "x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k8s.ParseCustomKinds(tt.input)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchCustomKinds(t *testing.T) {
+	kinds, err := k8s.ParseCustomKinds(
+		"serving.knative.dev/v1/Service=spec.template.spec.containers;" +
+			"example.com/v1/Cluster=spec.pools[*].containers,spec.broker")
+	require.NoError(t, err)
+	patcher := &k8s.NodeResourcePatcher{CustomKinds: kinds}
+	resCfg := k8s.ResourceConfig{
+		Requests: resourceList("cpu=100m,memory=128Mi"),
+		Limits:   resourceList("cpu=200m,memory=256Mi"),
+	}
+
+	t.Run("knative service", func(t *testing.T) {
+		input := `apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: hello
+spec:
+  template:
+    metadata:
+      annotations:
+        autoscaling.knative.dev/target: "10"
+    spec:
+      containerConcurrency: 0
+      containers:
+      - image: hello
+        resources:
+          limits:
+            cpu: "1"
+  traffic:
+  - latestRevision: true
+    percent: 100
+`
+		want := `apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: hello
+spec:
+  template:
+    metadata:
+      annotations:
+        autoscaling.knative.dev/target: "10"
+    spec:
+      containerConcurrency: 0
+      containers:
+      - image: hello
+        resources:
+          limits:
+            cpu: "200m"
+            memory: 256Mi
+          requests:
+            cpu: 100m
+            memory: 128Mi
+  traffic:
+  - latestRevision: true
+    percent: 100
+`
+		got, err := patcher.Patch(t.Context(), []byte(input), resCfg)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	})
+
+	t.Run("wildcard paths and single container mappings", func(t *testing.T) {
+		input := `apiVersion: example.com/v1
+kind: Cluster
+spec:
+  pools:
+  - containers:
+    - name: pool-a
+  - containers:
+    - name: pool-b
+  broker:
+    replicas: 3
+`
+		cfg := k8s.ResourceConfig{Containers: []k8s.ContainerConfig{
+			{Selector: "pool-*", Requests: resCfg.Requests, Limits: resCfg.Limits},
+			{Selector: "broker", Requests: resCfg.Limits, Limits: resCfg.Limits},
+		}}
+		want := `apiVersion: example.com/v1
+kind: Cluster
+spec:
+  pools:
+  - containers:
+    - name: pool-a
+      resources:
+        requests:
+          cpu: 100m
+          memory: 128Mi
+        limits:
+          cpu: 200m
+          memory: 256Mi
+  - containers:
+    - name: pool-b
+      resources:
+        requests:
+          cpu: 100m
+          memory: 128Mi
+        limits:
+          cpu: 200m
+          memory: 256Mi
+  broker:
+    replicas: 3
+    resources:
+      requests:
+        cpu: 200m
+        memory: 256Mi
+      limits:
+        cpu: 200m
+        memory: 256Mi
+`
+		got, err := patcher.Patch(t.Context(), []byte(input), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	})
+
+	t.Run("api version must match", func(t *testing.T) {
+		_, err := patcher.Patch(t.Context(), []byte("apiVersion: v1\nkind: Service\nspec:\n  ports: []\n"), resCfg)
+		assert.ErrorContains(t, err, "unsupported kind: Service")
+	})
+}
@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// document is a single YAML document of a multi-document stream. The
+// separator line that introduced it is kept verbatim so that the stream can
+// be re-assembled byte-for-byte.
+type document struct {
+	separator []byte
+	body      []byte
+}
+
+// isSeparator reports whether line is a YAML document separator ("---",
+// optionally followed by whitespace or a comment).
+func isSeparator(line []byte) bool {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+	rest := line[3:]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// splitDocuments splits a YAML stream into its documents. Concatenating the
+// separator and body of every returned document yields the original input.
+func splitDocuments(data []byte) []document {
+	docs := []document{{}}
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		data = data[len(line):]
+
+		if isSeparator(line) {
+			docs = append(docs, document{separator: line})
+			continue
+		}
+		cur := &docs[len(docs)-1]
+		cur.body = append(cur.body, line...)
+	}
+	return docs
+}
+
+// joinDocuments is the inverse of splitDocuments.
+func joinDocuments(docs []document) []byte {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		buf.Write(doc.separator)
+		buf.Write(doc.body)
+	}
+	return buf.Bytes()
+}
+
+// isEmptyDocument reports whether body holds nothing but whitespace and comments.
+func isEmptyDocument(body []byte) bool {
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// unsupportedKindError is returned by a documentPatcher for documents whose
+// kind it does not handle. Such documents are passed through unchanged.
+type unsupportedKindError struct {
+	kind string
+}
+
+func (e *unsupportedKindError) Error() string {
+	return fmt.Sprintf("unsupported kind: %s", e.kind)
+}
+
+// documentPatcher patches a single, non-empty YAML document.
+type documentPatcher func(body []byte) ([]byte, error)
+
+// patchDocuments applies patch to every non-empty document of file and
+// re-assembles the stream. It fails if no document could be patched.
+func patchDocuments(file []byte, patch documentPatcher) ([]byte, error) {
+	docs := splitDocuments(file)
+
+	var unsupported []string
+	patched := 0
+	for i, doc := range docs {
+		if isEmptyDocument(doc.body) {
+			continue
+		}
+
+		body, err := patch(doc.body)
+		var kindErr *unsupportedKindError
+		if errors.As(err, &kindErr) {
+			unsupported = append(unsupported, kindErr.kind)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs[i].body = body
+		patched++
+	}
+
+	if patched == 0 {
+		if len(unsupported) > 0 {
+			return nil, fmt.Errorf("unsupported kind: %s", strings.Join(unsupported, ", "))
+		}
+		return nil, fmt.Errorf("no Kubernetes documents found")
+	}
+	return joinDocuments(docs), nil
+}
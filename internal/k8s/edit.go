@@ -0,0 +1,425 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// editor records textual edits against the source of a YAML document. Node
+// positions reported by yaml.v3 are used to locate the bytes to change, so
+// everything that is not explicitly edited (comments, quoting, key order,
+// indentation) is preserved exactly.
+type editor struct {
+	src        []byte
+	lineStarts []int
+	step       int
+	edits      []edit
+}
+
+func newEditor(src []byte, root *yaml.Node) *editor {
+	e := &editor{src: src, lineStarts: []int{0}, step: 2}
+	for i, b := range src {
+		if b == '\n' {
+			e.lineStarts = append(e.lineStarts, i+1)
+		}
+	}
+	if step := detectIndent(root); step > 0 {
+		e.step = step
+	}
+	return e
+}
+
+// detectIndent returns the indentation step used by the document, derived
+// from the first nested block mapping, or 0 if none is found.
+func detectIndent(n *yaml.Node) int {
+	if n == nil {
+		return 0
+	}
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return detectIndent(n.Content[0])
+	}
+	if n.Kind != yaml.MappingNode || n.Style&yaml.FlowStyle != 0 {
+		return 0
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		if val.Kind == yaml.MappingNode && val.Style&yaml.FlowStyle == 0 && len(val.Content) > 0 {
+			if step := val.Content[0].Column - key.Column; step > 0 {
+				return step
+			}
+		}
+	}
+	return 0
+}
+
+// offset converts a 1-based line and column, as reported by yaml.v3, to a
+// byte offset in the source.
+func (e *editor) offset(line, column int) int {
+	if line < 1 || line > len(e.lineStarts) {
+		return len(e.src)
+	}
+	off := e.lineStarts[line-1]
+	for c := 1; c < column && off < len(e.src) && e.src[off] != '\n'; c++ {
+		_, size := utf8.DecodeRune(e.src[off:])
+		off += size
+	}
+	return off
+}
+
+// lineEnd returns the offset just past the newline that terminates line.
+func (e *editor) lineEnd(line int) int {
+	if line < len(e.lineStarts) {
+		return e.lineStarts[line]
+	}
+	return len(e.src)
+}
+
+// scalarEnd returns the offset just past the source text of a scalar node.
+func (e *editor) scalarEnd(n *yaml.Node) (int, error) {
+	start := e.offset(n.Line, n.Column)
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(e.src); i++ {
+			switch e.src[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(e.src); i++ {
+			if e.src[i] == '\'' {
+				if i+1 < len(e.src) && e.src[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, nil
+			}
+		}
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 && !strings.Contains(n.Value, "\n"):
+		if n.Tag == "!!null" && n.Value == "" {
+			return start, nil
+		}
+		end := start + len(n.Value)
+		if end <= len(e.src) && string(e.src[start:end]) == n.Value {
+			return end, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot locate value %q at line %d", n.Value, n.Line)
+}
+
+// flowEnd returns the offset just past a flow-style collection.
+func (e *editor) flowEnd(n *yaml.Node) (int, error) {
+	closing := byte('}')
+	if n.Kind == yaml.SequenceNode {
+		closing = ']'
+	}
+	from := e.offset(n.Line, n.Column) + 1
+	if len(n.Content) > 0 {
+		end, err := e.nodeEnd(n.Content[len(n.Content)-1])
+		if err != nil {
+			return 0, err
+		}
+		from = end
+	}
+	if i := bytes.IndexByte(e.src[from:], closing); i >= 0 {
+		return from + i + 1, nil
+	}
+	return 0, fmt.Errorf("unterminated flow collection at line %d", n.Line)
+}
+
+// nodeEnd returns the offset just past the last character of an inline node.
+func (e *editor) nodeEnd(n *yaml.Node) (int, error) {
+	if n.Kind == yaml.ScalarNode {
+		return e.scalarEnd(n)
+	}
+	if n.Style&yaml.FlowStyle != 0 {
+		return e.flowEnd(n)
+	}
+	return 0, fmt.Errorf("unexpected block node at line %d", n.Line)
+}
+
+// lastLine returns the last line occupied by the source text of n, an entry
+// of a block collection indented by indent columns. Block scalars and plain
+// scalars continued over several lines end at their last continuation line,
+// flow collections at their closing bracket.
+func (e *editor) lastLine(n *yaml.Node, indent int) (int, error) {
+	switch {
+	case n.Kind == yaml.AliasNode:
+		return n.Line, nil
+	case n.Kind != yaml.ScalarNode && n.Style&yaml.FlowStyle != 0:
+		end, err := e.flowEnd(n)
+		if err != nil {
+			return 0, err
+		}
+		return e.lineOf(end - 1), nil
+	case n.Kind == yaml.MappingNode && len(n.Content) > 0:
+		return e.lastLine(n.Content[len(n.Content)-1], n.Content[0].Column-1)
+	case n.Kind == yaml.SequenceNode && len(n.Content) > 0:
+		return e.lastLine(n.Content[len(n.Content)-1], n.Column-1)
+	case n.Kind != yaml.ScalarNode:
+		return n.Line, nil
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		header := bytes.Fields(e.src[e.offset(n.Line, n.Column):e.lineEnd(n.Line)])
+		keep := len(header) > 0 && bytes.ContainsRune(header[0], '+')
+		return e.continuedLine(n.Line, indent, true, keep), nil
+	}
+	end, err := e.scalarEnd(n)
+	switch {
+	case err == nil && end > e.offset(n.Line, n.Column):
+		return e.lineOf(end - 1), nil
+	case err == nil:
+		return n.Line, nil
+	case n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0:
+		return e.continuedLine(n.Line, indent, false, false), nil
+	}
+	return 0, err
+}
+
+// continuedLine returns the last line of a scalar that starts on line and
+// continues on the following lines indented by more than indent columns.
+// Comments end plain scalars but not block scalars, and trailing blank lines
+// are only part of block scalars with the keep chomping indicator.
+func (e *editor) continuedLine(line, indent int, block, keep bool) int {
+	last := line
+	for l := line + 1; l <= len(e.lineStarts); l++ {
+		text := e.src[e.lineStarts[l-1]:e.lineEnd(l)]
+		trimmed := bytes.TrimLeft(text, " ")
+		content := bytes.TrimSpace(trimmed)
+		if len(content) == 0 {
+			if keep {
+				last = l
+			}
+			continue
+		}
+		if len(text)-len(trimmed) <= indent || !block && content[0] == '#' {
+			break
+		}
+		last = l
+	}
+	return last
+}
+
+// lineOf returns the 1-based line holding the byte at offset off.
+func (e *editor) lineOf(off int) int {
+	return sort.Search(len(e.lineStarts), func(i int) bool { return e.lineStarts[i] > off })
+}
+
+// replace schedules the replacement of src[start:end] with text.
+func (e *editor) replace(start, end int, text string) {
+	e.edits = append(e.edits, edit{start: start, end: end, text: text})
+}
+
+// insertAfterLine schedules the insertion of text after the given line.
+func (e *editor) insertAfterLine(line int, text string) {
+	off := e.lineEnd(line)
+	if off == len(e.src) && len(e.src) > 0 && e.src[len(e.src)-1] != '\n' {
+		text = "\n" + text
+	}
+	e.replace(off, off, text)
+}
+
+// setScalar replaces the value of a scalar node, keeping its quoting style.
+func (e *editor) setScalar(n *yaml.Node, value string) error {
+	start := e.offset(n.Line, n.Column)
+	end, err := e.scalarEnd(n)
+	if err != nil {
+		return err
+	}
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		value = `"` + value + `"`
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		value = `'` + value + `'`
+	}
+	e.replace(start, end, value)
+	return nil
+}
+
+// entry is a key with either a scalar value or nested entries.
+type entry struct {
+	key      string
+	value    string
+	children []entry
+}
+
+// formatScalar renders value as a YAML string scalar, quoting it when it
+// would otherwise be read as a number or another non-string type.
+func formatScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// renderBlock renders entries as block-style YAML at the given indentation.
+func (e *editor) renderBlock(entries []entry, indent int) string {
+	var sb strings.Builder
+	for _, en := range entries {
+		sb.WriteString(strings.Repeat(" ", indent))
+		sb.WriteString(en.key)
+		sb.WriteString(":")
+		if en.children != nil {
+			sb.WriteString("\n")
+			sb.WriteString(e.renderBlock(en.children, indent+e.step))
+			continue
+		}
+		sb.WriteString(" ")
+		sb.WriteString(formatScalar(en.value))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// renderFlow renders entries as the body of a flow-style mapping.
+func renderFlow(entries []entry) string {
+	parts := make([]string, 0, len(entries))
+	for _, en := range entries {
+		if en.children != nil {
+			parts = append(parts, en.key+": {"+renderFlow(en.children)+"}")
+			continue
+		}
+		parts = append(parts, en.key+": "+formatScalar(en.value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// addEntries schedules the addition of entries to the mapping held by value,
+// whose key is key. value may be an empty or null node, in which case it is
+// replaced by a block mapping.
+func (e *editor) addEntries(key, value *yaml.Node, entries []entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	switch {
+	case value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle != 0 && len(value.Content) > 0:
+		end, err := e.flowEnd(value)
+		if err != nil {
+			return err
+		}
+		e.replace(end-1, end-1, ", "+renderFlow(entries))
+	case value.Kind == yaml.MappingNode && len(value.Content) > 0:
+		line, err := e.lastLine(value, value.Content[0].Column-1)
+		if err != nil {
+			return err
+		}
+		e.insertAfterLine(line, e.renderBlock(entries, value.Content[0].Column-1))
+	case value.Kind == yaml.MappingNode || value.Tag == "!!null":
+		// Empty value such as "resources: {}" or "resources:": drop the
+		// inline token and continue as a block mapping on the next lines.
+		if value.Kind == yaml.MappingNode || value.Value != "" {
+			keyEnd, err := e.scalarEnd(key)
+			if err != nil {
+				return err
+			}
+			end, err := e.nodeEnd(value)
+			if err != nil {
+				return err
+			}
+			if colon := bytes.IndexByte(e.src[keyEnd:end], ':'); colon >= 0 {
+				e.replace(keyEnd+colon+1, end, "")
+			}
+		}
+		e.insertAfterLine(key.Line, e.renderBlock(entries, key.Column-1+e.step))
+	default:
+		return fmt.Errorf("%s at line %d is not a mapping", key.Value, key.Line)
+	}
+	return nil
+}
+
+// removeEntries schedules the removal of keys from mapping m. Entries of
+// block mappings are removed with their whole lines; at least one entry of a
+// flow mapping must be kept.
+func (e *editor) removeEntries(m *yaml.Node, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	remove := map[string]bool{}
+	for _, k := range keys {
+		remove[k] = true
+	}
+
+	if m.Style&yaml.FlowStyle == 0 {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key := m.Content[i]
+			if !remove[key.Value] {
+				continue
+			}
+			start := e.offset(key.Line, 1)
+			if indent := e.offset(key.Line, key.Column); len(bytes.TrimSpace(e.src[start:indent])) > 0 {
+				return fmt.Errorf("cannot remove %s at line %d", key.Value, key.Line)
+			}
+			line, err := e.lastLine(m.Content[i+1], key.Column-1)
+			if err != nil {
+				return err
+			}
+			e.replace(start, e.lineEnd(line), "")
+		}
+		return nil
+	}
+
+	// In flow mappings, an entry followed by a kept entry is removed up to
+	// the start of the next key, trailing entries from the end of the last
+	// kept value.
+	lastKept := -1
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if !remove[m.Content[i].Value] {
+			lastKept = i
+		}
+	}
+	if lastKept < 0 {
+		return fmt.Errorf("cannot remove every entry of the mapping at line %d", m.Line)
+	}
+	for i := 0; i+1 < lastKept; i += 2 {
+		if remove[m.Content[i].Value] {
+			next := m.Content[i+2]
+			e.replace(e.offset(m.Content[i].Line, m.Content[i].Column), e.offset(next.Line, next.Column), "")
+		}
+	}
+	if lastKept+2 < len(m.Content) {
+		start, err := e.nodeEnd(m.Content[lastKept+1])
+		if err != nil {
+			return err
+		}
+		end, err := e.nodeEnd(m.Content[len(m.Content)-1])
+		if err != nil {
+			return err
+		}
+		e.replace(start, end, "")
+	}
+	return nil
+}
+
+// apply returns the source with all scheduled edits applied. Insertions are
+// applied before removals starting at the same offset.
+func (e *editor) apply() []byte {
+	sort.SliceStable(e.edits, func(i, j int) bool {
+		if e.edits[i].start != e.edits[j].start {
+			return e.edits[i].start < e.edits[j].start
+		}
+		return e.edits[i].end < e.edits[j].end
+	})
+	var buf bytes.Buffer
+	last := 0
+	for _, ed := range e.edits {
+		buf.Write(e.src[last:ed.start])
+		buf.WriteString(ed.text)
+		last = ed.end
+	}
+	buf.Write(e.src[last:])
+	return buf.Bytes()
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ContainerResources holds the resources of a single container of a manifest.
+type ContainerResources struct {
+	Kind      string
+	Name      string
+	Container string
+	Requests  corev1.ResourceList
+	Limits    corev1.ResourceList
+}
+
+// String identifies the container, e.g. "Deployment/api container app".
+func (c ContainerResources) String() string {
+	return fmt.Sprintf("%s/%s container %s", c.Kind, c.Name, c.Container)
+}
+
+// Resources returns the resources of every container of the supported
+// documents in file. Documents of other kinds are skipped.
+func (p *NodeResourcePatcher) Resources(file []byte) ([]ContainerResources, error) {
+	var all []ContainerResources
+	for _, doc := range splitDocuments(file) {
+		if isEmptyDocument(doc.body) {
+			continue
+		}
+		_, root, kind, err := parseDocument(doc.body)
+		if err != nil {
+			return nil, err
+		}
+		containers, refs, err := p.findContainers(root, kind)
+		var kindErr *unsupportedKindError
+		if errors.As(err, &kindErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := scalarValue(lookupPath(root, []string{"metadata"}), "name")
+		for i, c := range containers {
+			cr := ContainerResources{Kind: kind, Name: name, Container: refs[i].name}
+			_, res := mappingEntry(c, "resources")
+			for _, section := range []struct {
+				name string
+				list *corev1.ResourceList
+			}{
+				{"requests", &cr.Requests},
+				{"limits", &cr.Limits},
+			} {
+				_, sec := mappingEntry(res, section.name)
+				if sec == nil || sec.Kind != yaml.MappingNode {
+					continue
+				}
+				*section.list = corev1.ResourceList{}
+				for j := 0; j+1 < len(sec.Content); j += 2 {
+					key, value := sec.Content[j], sec.Content[j+1]
+					q, err := resource.ParseQuantity(value.Value)
+					if err != nil {
+						return nil, fmt.Errorf("%s: invalid quantity %q for %s.%s at line %d", cr, value.Value, section.name, key.Value, value.Line)
+					}
+					(*section.list)[corev1.ResourceName(key.Value)] = q
+				}
+			}
+			all = append(all, cr)
+		}
+	}
+	return all, nil
+}
+
+// EqualResources reports whether a and b list the same containers with
+// semantically equal quantities, e.g. 1000m and 1, or 1Gi and 1024Mi.
+func EqualResources(a, b []ContainerResources) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Kind != b[i].Kind || a[i].Name != b[i].Name || a[i].Container != b[i].Container ||
+			!equalResourceLists(a[i].Requests, b[i].Requests) || !equalResourceLists(a[i].Limits, b[i].Limits) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalResourceLists(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, q := range a {
+		other, ok := b[name]
+		if !ok || q.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeResourcePatcher_Resources(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  initContainers:
+  - name: migrate
+  containers:
+  - name: app
+    resources:
+      requests: {cpu: 100m}
+      limits:
+        cpu: "1"
+        nvidia.com/gpu: 1
+---
+kind: ConfigMap
+metadata:
+  name: settings
+`
+
+	got, err := (&k8s.NodeResourcePatcher{}).Resources([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, []k8s.ContainerResources{
+		{
+			Kind:      "Pod",
+			Name:      "web",
+			Container: "app",
+			Requests:  resourceList("cpu=100m"),
+			Limits:    resourceList("cpu=1,nvidia.com/gpu=1"),
+		},
+		{Kind: "Pod", Name: "web", Container: "migrate"},
+	}, got)
+	assert.Equal(t, "Pod/web container app", got[0].String())
+}
+
+func TestEqualResources(t *testing.T) {
+	patcher := &k8s.NodeResourcePatcher{}
+	list := func(s string) []k8s.ContainerResources {
+		res, err := patcher.Resources([]byte(s))
+		require.NoError(t, err)
+		return res
+	}
+	base := list("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      requests: {cpu: 1, memory: 1Gi}\n")
+
+	assert.True(t, k8s.EqualResources(base, list("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      requests:\n        memory: 1024Mi\n        cpu: 1000m\n")))
+	assert.False(t, k8s.EqualResources(base, list("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      requests: {cpu: 1, memory: 1Gi}\n      limits: {cpu: 1}\n")))
+	assert.False(t, k8s.EqualResources(base, list("kind: Pod\nspec:\n  containers:\n  - name: web\n    resources:\n      requests: {cpu: 1, memory: 1Gi}\n")))
+	assert.False(t, k8s.EqualResources(base, list("kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      requests: {cpu: 2, memory: 1Gi}\n")))
+}
@@ -1,116 +1,86 @@
 package k8s
 
 import (
-	"fmt"
+	"context"
+	"io"
 
-	appsv1 "k8s.io/api/apps/v1"
-	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
-	"sigs.k8s.io/yaml"
 )
 
 // ResourcePatcher defines the interface for patching resource requirements in K8S manifests
 type ResourcePatcher interface {
-	Patch([]byte, ResourceConfig) ([]byte, error)
+	Patch(context.Context, []byte, ResourceConfig) ([]byte, error)
 }
 
-// DefaultResourcePatcher implements ResourcePatcher for common K8S kinds
+// DefaultResourcePatcher implements ResourcePatcher for common K8S kinds.
+// Manifests are never decoded into the typed k8s.io/api structs, which would
+// drop every field they do not know; see NodeResourcePatcher.
 type DefaultResourcePatcher struct{}
 
-// ResourceConfig holds parsed resource quantities for CPU and memory.
+// ResourceConfig holds the resource quantities to set, keyed by resource
+// name such as cpu, memory, ephemeral-storage, hugepages-2Mi or
+// nvidia.com/gpu. Resources that are not listed keep their current value in
+// the manifest.
 type ResourceConfig struct {
-	CPURequest resource.Quantity
-	MemRequest resource.Quantity
-	CPULimit   resource.Quantity
-	MemLimit   resource.Quantity
+	Requests corev1.ResourceList
+	Limits   corev1.ResourceList
+	// Remove lists entries to delete, in the form "<requests|limits>.<name>",
+	// e.g. "limits.cpu".
+	Remove []string
+	// Adjustments change resources relative to their current values, after
+	// the values above have been applied.
+	Adjustments []Adjustment
+	// Rounding holds, per resource name, the unit adjusted values are
+	// rounded up to. Resources without an entry use 1m for CPU, 1Mi for
+	// memory, storage and huge pages, and 1 otherwise.
+	Rounding corev1.ResourceList
+	// Containers selects the containers to patch, each with its own values.
+	// When none of them targets app containers, other than overrides, the
+	// values above are applied to the first app container.
+	Containers []ContainerConfig
+	// Warnings receives the warnings of a patch, such as a manifest with
+	// several app containers of which only the first is patched. Nil
+	// discards them.
+	Warnings io.Writer
 }
 
-func unmarshalResource[T any](data []byte) (*T, error) {
-	var obj T
-	if err := yaml.Unmarshal(data, &obj); err != nil {
-		return nil, err
-	}
-	return &obj, nil
+// ContainerConfig holds the resources applied to the containers matched by
+// Selector.
+type ContainerConfig struct {
+	// Selector matches container names. It is either an exact name, a glob
+	// such as "worker-*", or a regular expression enclosed in slashes such
+	// as "/^app(-v2)?$/".
+	Selector string
+	// Type restricts the selector to app containers, init containers,
+	// native sidecars or a combination of them. Zero means AppContainers.
+	Type        ContainerType
+	Requests    corev1.ResourceList
+	Limits      corev1.ResourceList
+	Remove      []string
+	Adjustments []Adjustment
+	// Override only changes the values of the containers matched by
+	// Selector. Unlike other configs for app containers, it leaves the first
+	// app container selected when no other config targets app containers.
+	Override bool
 }
 
-type typeMeta struct {
-	Kind string `yaml:"kind"`
-}
-
-func getKind(data []byte) (string, error) {
-	var tm typeMeta
-	if err := yaml.Unmarshal(data, &tm); err != nil {
-		return "", fmt.Errorf("YAML unmarshal error: %v", err)
-	}
-	return tm.Kind, nil
-}
-
-// containerExtractor is a function that extracts the manifest object and containers from a resource file.
-type containerExtractor func(file []byte) (any, []corev1.Container, error)
-
-// newExtractor creates a containerExtractor for a specific Kubernetes resource type using generics.
-func newExtractor[T any](getContainers func(obj *T) []corev1.Container) containerExtractor {
-	return func(file []byte) (any, []corev1.Container, error) {
-		obj, err := unmarshalResource[T](file)
-		if err != nil {
-			return nil, nil, err
-		}
-		return obj, getContainers(obj), nil
-	}
-}
-
-var extractorMap = map[string]containerExtractor{
-	"Deployment": newExtractor(func(o *appsv1.Deployment) []corev1.Container {
-		return o.Spec.Template.Spec.Containers
-	}),
-	"DaemonSet": newExtractor(func(o *appsv1.DaemonSet) []corev1.Container {
-		return o.Spec.Template.Spec.Containers
-	}),
-	"StatefulSet": newExtractor(func(o *appsv1.StatefulSet) []corev1.Container {
-		return o.Spec.Template.Spec.Containers
-	}),
-	"Pod": newExtractor(func(o *corev1.Pod) []corev1.Container {
-		return o.Spec.Containers
-	}),
-	"Job": newExtractor(func(o *batchv1.Job) []corev1.Container {
-		return o.Spec.Template.Spec.Containers
-	}),
-}
-
-func (p *DefaultResourcePatcher) Patch(file []byte, resCfg ResourceConfig) ([]byte, error) {
-	kind, err := getKind(file)
-	if err != nil {
-		return nil, err
-	}
-
-	extractor, ok := extractorMap[kind]
-	if !ok {
-		return nil, fmt.Errorf("unsupported kind: %s", kind)
-	}
-
-	manifest, containers, err := extractor(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract containers for kind %s: %w", kind, err)
-	}
-
-	if len(containers) == 0 {
-		return nil, fmt.Errorf("no containers found in %s", kind)
-	}
-	if len(containers) > 1 {
-		fmt.Printf("Warning: Multiple containers found in %s, updating only the first one\n", kind)
-	}
-
-	containers[0].Resources = corev1.ResourceRequirements{
-		Requests: map[corev1.ResourceName]resource.Quantity{
-			corev1.ResourceCPU:    resCfg.CPURequest,
-			corev1.ResourceMemory: resCfg.MemRequest,
-		},
-		Limits: map[corev1.ResourceName]resource.Quantity{
-			corev1.ResourceCPU:    resCfg.CPULimit,
-			corev1.ResourceMemory: resCfg.MemLimit,
-		},
-	}
+// ContainerType identifies the containers of a pod spec a ContainerConfig
+// applies to. Values can be combined, e.g. InitContainers|SidecarContainers.
+type ContainerType int
+
+const (
+	// AppContainers are the entries of spec.containers.
+	AppContainers ContainerType = 1 << iota
+	// InitContainers are the entries of spec.initContainers that run to
+	// completion before the app containers start.
+	InitContainers
+	// SidecarContainers are the entries of spec.initContainers with
+	// restartPolicy: Always, i.e. native sidecars.
+	SidecarContainers
+)
 
-	return yaml.Marshal(manifest)
+// Patch updates the resources of every supported document in file. Documents
+// of other kinds, as well as empty ones, are passed through unchanged.
+func (p *DefaultResourcePatcher) Patch(ctx context.Context, file []byte, resCfg ResourceConfig) ([]byte, error) {
+	return (&NodeResourcePatcher{}).Patch(ctx, file, resCfg)
 }
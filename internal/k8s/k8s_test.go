@@ -1,6 +1,7 @@
 package k8s_test
 
 import (
+	"strings"
 	"testing"
 
 	"k8s-resource-adjustment/internal/k8s"
@@ -8,17 +9,24 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/yaml"
 )
 
+// resourceList parses s with k8s.ParseResourceList, panicking on error.
+func resourceList(s string) corev1.ResourceList {
+	list, err := k8s.ParseResourceList(s)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
 func TestDefaultResourcePatcher_Patch(t *testing.T) {
 	resCfg := k8s.ResourceConfig{
-		CPURequest: resource.MustParse("100m"),
-		MemRequest: resource.MustParse("128Mi"),
-		CPULimit:   resource.MustParse("200m"),
-		MemLimit:   resource.MustParse("256Mi"),
+		Requests: resourceList("cpu=100m,memory=128Mi"),
+		Limits:   resourceList("cpu=200m,memory=256Mi"),
 	}
 
 	tests := []struct {
@@ -46,10 +54,10 @@ spec:
 				var deployment appsv1.Deployment
 				err := yaml.Unmarshal(patchedYAML, &deployment)
 				require.NoError(t, err)
-				assert.Equal(t, resCfg.CPURequest, deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
-				assert.Equal(t, resCfg.MemRequest, deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory])
-				assert.Equal(t, resCfg.CPULimit, deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
-				assert.Equal(t, resCfg.MemLimit, deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory])
+				assert.Equal(t, resCfg.Requests[corev1.ResourceCPU], deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+				assert.Equal(t, resCfg.Requests[corev1.ResourceMemory], deployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory])
+				assert.Equal(t, resCfg.Limits[corev1.ResourceCPU], deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
+				assert.Equal(t, resCfg.Limits[corev1.ResourceMemory], deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory])
 			},
 		},
 		{
@@ -70,12 +78,122 @@ spec:
 				var daemonset appsv1.DaemonSet
 				err := yaml.Unmarshal(patchedYAML, &daemonset)
 				require.NoError(t, err)
-				assert.Equal(t, resCfg.CPURequest, daemonset.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
-				assert.Equal(t, resCfg.MemRequest, daemonset.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory])
-				assert.Equal(t, resCfg.CPULimit, daemonset.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
-				assert.Equal(t, resCfg.MemLimit, daemonset.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory])
+				assert.Equal(t, resCfg.Requests[corev1.ResourceCPU], daemonset.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+				assert.Equal(t, resCfg.Requests[corev1.ResourceMemory], daemonset.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory])
+				assert.Equal(t, resCfg.Limits[corev1.ResourceCPU], daemonset.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
+				assert.Equal(t, resCfg.Limits[corev1.ResourceMemory], daemonset.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory])
+			},
+		},
+		{
+			name: "valid cronjob",
+			inputFile: []byte(`
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test-cronjob
+spec:
+  schedule: "*/5 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: test-container
+            image: busybox`),
+			wantErr: false,
+			verify: func(t *testing.T, patchedYAML []byte) {
+				var cronjob batchv1.CronJob
+				err := yaml.Unmarshal(patchedYAML, &cronjob)
+				require.NoError(t, err)
+				assert.Equal(t, "*/5 * * * *", cronjob.Spec.Schedule)
+				assert.Equal(t, resCfg.Requests[corev1.ResourceCPU], cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+				assert.Equal(t, resCfg.Limits[corev1.ResourceMemory], cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory])
+			},
+		},
+		{
+			name: "valid replicationcontroller",
+			inputFile: []byte(`
+apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: test-rc
+spec:
+  template:
+    spec:
+      containers:
+      - name: test-container
+        image: nginx`),
+			wantErr: false,
+			verify: func(t *testing.T, patchedYAML []byte) {
+				var rc corev1.ReplicationController
+				err := yaml.Unmarshal(patchedYAML, &rc)
+				require.NoError(t, err)
+				assert.Equal(t, resCfg.Limits[corev1.ResourceCPU], rc.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
+			},
+		},
+		{
+			name: "multiple documents",
+			inputFile: []byte(`# leading comment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        image: nginx
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api # kept as is
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: busybox
+`),
+			wantErr: false,
+			verify: func(t *testing.T, patchedYAML []byte) {
+				docs := strings.Split(string(patchedYAML), "\n---\n")
+				require.Len(t, docs, 3)
+
+				var deployment appsv1.Deployment
+				require.NoError(t, yaml.Unmarshal([]byte(docs[0]), &deployment))
+				assert.Equal(t, "api", deployment.Name)
+				assert.Equal(t, resCfg.Limits[corev1.ResourceCPU], deployment.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceCPU])
+
+				assert.Equal(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: api # kept as is", docs[1])
+
+				var job batchv1.Job
+				require.NoError(t, yaml.Unmarshal([]byte(docs[2]), &job))
+				assert.Equal(t, "migrate", job.Name)
+				assert.Equal(t, resCfg.Requests[corev1.ResourceMemory], job.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceMemory])
 			},
 		},
+		{
+			name: "only unsupported documents",
+			inputFile: []byte(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: test-service
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config`),
+			wantErr:     true,
+			errContains: "unsupported kind: Service, ConfigMap",
+		},
 		{
 			name: "unsupported kind",
 			inputFile: []byte(`
@@ -112,7 +230,7 @@ spec:
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFile, err := patcher.Patch(tt.inputFile, resCfg)
+			gotFile, err := patcher.Patch(t.Context(), tt.inputFile, resCfg)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -127,3 +245,146 @@ spec:
 		})
 	}
 }
+
+func TestDefaultResourcePatcher_PatchSelectors(t *testing.T) {
+	input := []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+      - name: app
+        image: nginx`)
+	app := k8s.ContainerConfig{
+		Selector: "/^app$/",
+		Requests: resourceList("cpu=100m,memory=128Mi"),
+		Limits:   resourceList("cpu=200m,memory=256Mi"),
+	}
+	patcher := &k8s.DefaultResourcePatcher{}
+
+	patched, err := patcher.Patch(t.Context(), input, k8s.ResourceConfig{Containers: []k8s.ContainerConfig{app}})
+	require.NoError(t, err)
+	var deployment appsv1.Deployment
+	require.NoError(t, yaml.Unmarshal(patched, &deployment))
+	assert.Empty(t, deployment.Spec.Template.Spec.Containers[0].Resources.Limits)
+	assert.Equal(t, app.Limits[corev1.ResourceCPU], deployment.Spec.Template.Spec.Containers[1].Resources.Limits[corev1.ResourceCPU])
+
+	sidecar := app
+	sidecar.Selector = "istio-*"
+	sidecar.Type = k8s.SidecarContainers
+	patched, err = patcher.Patch(t.Context(), []byte(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-pod
+spec:
+  initContainers:
+  - name: istio-proxy
+    restartPolicy: Always
+  containers:
+  - name: app`), k8s.ResourceConfig{Containers: []k8s.ContainerConfig{sidecar}})
+	require.NoError(t, err)
+	var pod corev1.Pod
+	require.NoError(t, yaml.Unmarshal(patched, &pod))
+	assert.Equal(t, sidecar.Limits[corev1.ResourceMemory], pod.Spec.InitContainers[0].Resources.Limits[corev1.ResourceMemory])
+
+	missing := app
+	missing.Selector = "worker"
+	_, err = patcher.Patch(t.Context(), input, k8s.ResourceConfig{Containers: []k8s.ContainerConfig{app, missing}})
+	assert.ErrorContains(t, err, `container selector "worker" matched no container`)
+}
+
+func TestDefaultResourcePatcher_PreservesUnknownFields(t *testing.T) {
+	base := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: api
+        image: nginx
+        resources:
+          requests:
+            cpu: 50m
+            ephemeral-storage: 1Gi
+          limits:
+            nvidia.com/gpu: 1
+          claims:
+          - name: gpu
+`
+	// Each extra is inserted after the line it is keyed on.
+	extras := []struct {
+		name  string
+		after string
+		lines string
+	}{
+		{name: "vendor extension", after: "kind: Deployment", lines: "x-vendor-extension: {enabled: true, tier: gold}"},
+		{name: "unknown metadata field", after: "  name: api", lines: "  futureMetadata:\n    owner: team-a"},
+		{name: "unknown spec field", after: "  replicas: 2", lines: "  rolloutWindow: 30m"},
+		{name: "newer pod spec field", after: "    spec:", lines: "      hostUsers: false\n      schedulingGates:\n      - name: example.com/gate"},
+		{name: "typo in container", after: "        image: nginx", lines: "        imagePullPolic: Always"},
+		{name: "newer container field", after: "        image: nginx", lines: "        resizePolicy:\n        - resourceName: cpu\n          restartPolicy: NotRequired"},
+		{name: "unknown resources field", after: "        resources:", lines: "          x-burstable: true"},
+		{name: "status", after: "          - name: gpu", lines: "status:\n  observedGeneration: 3\n  conditions: []"},
+	}
+
+	insert := func(input, after, lines string) string {
+		i := strings.Index(input, after+"\n")
+		require.GreaterOrEqual(t, i, 0, "anchor %q not found", after)
+		i += len(after) + 1
+		return input[:i] + lines + "\n" + input[i:]
+	}
+
+	// withoutPatchedValues decodes a manifest into an unstructured map and
+	// drops the values the patcher is allowed to change.
+	withoutPatchedValues := func(manifest string) map[string]any {
+		var obj map[string]any
+		require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj))
+		containers := obj["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]any)
+		resources := containers[0].(map[string]any)["resources"].(map[string]any)
+		for _, section := range []string{"requests", "limits"} {
+			values := resources[section].(map[string]any)
+			delete(values, "cpu")
+			delete(values, "memory")
+		}
+		return obj
+	}
+
+	patcher := &k8s.DefaultResourcePatcher{}
+	resCfg := k8s.ResourceConfig{
+		Requests: resourceList("cpu=100m,memory=128Mi"),
+		Limits:   resourceList("cpu=200m,memory=256Mi"),
+	}
+
+	check := func(t *testing.T, input string) {
+		got, err := patcher.Patch(t.Context(), []byte(input), resCfg)
+		require.NoError(t, err)
+		assert.Equal(t, withoutPatchedValues(input), withoutPatchedValues(string(got)))
+
+		var deployment appsv1.Deployment
+		require.NoError(t, yaml.Unmarshal(got, &deployment))
+		res := deployment.Spec.Template.Spec.Containers[0].Resources
+		assert.True(t, resCfg.Requests[corev1.ResourceCPU].Equal(res.Requests[corev1.ResourceCPU]))
+		assert.True(t, resCfg.Limits[corev1.ResourceMemory].Equal(res.Limits[corev1.ResourceMemory]))
+	}
+
+	all := base
+	for _, extra := range extras {
+		input := insert(base, extra.after, extra.lines)
+		all = insert(all, extra.after, extra.lines)
+		t.Run(extra.name, func(t *testing.T) {
+			check(t, input)
+		})
+	}
+	t.Run("all extras", func(t *testing.T) {
+		check(t, all)
+	})
+}
@@ -0,0 +1,325 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// NodeResourcePatcher implements ResourcePatcher on the YAML node tree of the
+// manifest. Only the quantities that actually change are rewritten, so
+// comments, key order and formatting of the rest of the file are preserved.
+// Fields the patcher does not know about, including those of custom kinds,
+// are never decoded and therefore survive untouched.
+type NodeResourcePatcher struct {
+	// CustomKinds registers additional kinds, such as CRDs, and where their
+	// containers are located. They take precedence over the built-in kinds.
+	CustomKinds []CustomKind
+}
+
+// podSpecPathMap holds, per kind, the path from the document root to the pod
+// spec holding the containers and initContainers lists.
+var podSpecPathMap = map[string][]string{
+	"Deployment":            {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Pod":                   {"spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+	// Argo Rollouts (argoproj.io/v1alpha1). Rollouts that reference their
+	// workload through spec.workloadRef carry no containers to patch and are
+	// passed through unchanged, see referencesWorkload.
+	"Rollout": {"spec", "template", "spec"},
+}
+
+// referencesWorkload reports whether root is an Argo Rollout that takes its
+// pod template from the workload named by spec.workloadRef.
+func referencesWorkload(root *yaml.Node, kind string) bool {
+	return kind == "Rollout" &&
+		lookupPath(root, []string{"spec", "workloadRef"}) != nil &&
+		lookupPath(root, []string{"spec", "template"}) == nil
+}
+
+// resourceSections are the sections of a resources block holding quantities.
+var resourceSections = []string{"requests", "limits"}
+
+// resourceValue is a single entry of a resources block to set or, if remove
+// is true, to delete, e.g. requests.cpu.
+type resourceValue struct {
+	section  string
+	name     string
+	quantity resource.Quantity
+	remove   bool
+}
+
+// parseResourceKey splits a key such as "limits.cpu" into section and name.
+func parseResourceKey(key string) (string, string, error) {
+	section, name, ok := strings.Cut(key, ".")
+	if !ok || name == "" || (section != "requests" && section != "limits") {
+		return "", "", fmt.Errorf("invalid resource key %q: expected <requests|limits>.<name>", key)
+	}
+	return section, name, nil
+}
+
+func (c ContainerConfig) values() ([]resourceValue, error) {
+	if err := validateResources(c.Requests, c.Limits); err != nil {
+		return nil, err
+	}
+	if err := validateAdjustments(c.Adjustments, c.Remove); err != nil {
+		return nil, err
+	}
+	var values []resourceValue
+	for _, section := range []struct {
+		name string
+		list corev1.ResourceList
+	}{
+		{"requests", c.Requests},
+		{"limits", c.Limits},
+	} {
+		for _, name := range ResourceNames(section.list) {
+			values = append(values, resourceValue{section: section.name, name: string(name), quantity: section.list[name]})
+		}
+	}
+	for _, key := range c.Remove {
+		section, name, err := parseResourceKey(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if v.section == section && v.name == name {
+				return nil, fmt.Errorf("resource %s is both set and removed", key)
+			}
+		}
+		values = append(values, resourceValue{section: section, name: name, remove: true})
+	}
+	return values, nil
+}
+
+// mappingEntry returns the key and value nodes of key in mapping m.
+func mappingEntry(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// lookupPath follows path through nested mappings starting at n.
+func lookupPath(n *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		_, n = mappingEntry(n, key)
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// sequenceItems returns the items of the sequence held by key in mapping m.
+func sequenceItems(m *yaml.Node, key string) []*yaml.Node {
+	_, seq := mappingEntry(m, key)
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return append([]*yaml.Node(nil), seq.Content...)
+}
+
+// scalarValue returns the value of the scalar held by key in mapping m.
+func scalarValue(m *yaml.Node, key string) string {
+	_, v := mappingEntry(m, key)
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return v.Value
+}
+
+// parseDocument parses a single YAML document and returns its root mapping
+// and kind.
+func parseDocument(body []byte) (*yaml.Node, *yaml.Node, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, "", fmt.Errorf("YAML unmarshal error: %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, "", fmt.Errorf("YAML unmarshal error: document is not a mapping")
+	}
+	root := doc.Content[0]
+	_, kind := mappingEntry(root, "kind")
+	if kind == nil {
+		return &doc, root, "", nil
+	}
+	return &doc, root, kind.Value, nil
+}
+
+func (p *NodeResourcePatcher) Patch(ctx context.Context, file []byte, resCfg ResourceConfig) ([]byte, error) {
+	matched := map[int]bool{}
+	out, err := patchDocuments(file, func(body []byte) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return p.patchDocument(body, resCfg, matched)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := unmatchedSelectors(resCfg, matched); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p *NodeResourcePatcher) patchDocument(body []byte, resCfg ResourceConfig, matched map[int]bool) ([]byte, error) {
+	doc, root, kind, err := parseDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	containers, refs, err := p.findContainers(root, kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		if referencesWorkload(root, kind) {
+			// The referenced workload is patched in its own document.
+			return body, nil
+		}
+		return nil, fmt.Errorf("no containers found in %s", kind)
+	}
+
+	selected, err := selectContainers(kind, refs, resCfg, matched)
+	if err != nil {
+		return nil, err
+	}
+
+	e := newEditor(body, doc)
+	for _, sel := range selected {
+		values, err := sel.cfg.values()
+		if err != nil {
+			return nil, err
+		}
+		values, err = adjustValues(containers[sel.index], values, sel.cfg.Adjustments, resCfg.Rounding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to patch %s: %w", kind, err)
+		}
+		if err := setResources(e, containers[sel.index], values); err != nil {
+			return nil, fmt.Errorf("failed to patch %s: %w", kind, err)
+		}
+	}
+	return e.apply(), nil
+}
+
+// findContainers returns the container nodes of a document along with their
+// names and types.
+func (p *NodeResourcePatcher) findContainers(root *yaml.Node, kind string) ([]*yaml.Node, []containerRef, error) {
+	if ck, ok := p.lookupCustomKind(scalarValue(root, "apiVersion"), kind); ok {
+		return customContainers(root, ck)
+	}
+
+	path, ok := podSpecPathMap[kind]
+	if !ok {
+		return nil, nil, &unsupportedKindError{kind: kind}
+	}
+
+	spec := lookupPath(root, path)
+	containers := sequenceItems(spec, "containers")
+	if len(containers) == 0 {
+		return nil, nil, nil
+	}
+
+	var refs []containerRef
+	for _, c := range containers {
+		refs = append(refs, containerRef{name: scalarValue(c, "name"), typ: AppContainers})
+	}
+	for _, c := range sequenceItems(spec, "initContainers") {
+		containers = append(containers, c)
+		refs = append(refs, containerRef{name: scalarValue(c, "name"), typ: containerType("initContainers", c)})
+	}
+	return containers, refs, nil
+}
+
+// setResources schedules the edits that apply values to the resources block
+// of container. Values that are already semantically equal are left
+// untouched, as is every entry that values does not mention. Sections, and
+// the resources block itself, are dropped when their last entry is removed.
+func setResources(e *editor, container *yaml.Node, values []resourceValue) error {
+	if container.Kind != yaml.MappingNode {
+		return fmt.Errorf("container at line %d is not a mapping", container.Line)
+	}
+
+	resKey, res := mappingEntry(container, "resources")
+
+	var missingSections []entry
+	var removedSections []string
+	for _, section := range resourceSections {
+		secKey, sec := mappingEntry(res, section)
+
+		var missing []entry
+		var removed []string
+		for _, v := range values {
+			if v.section != section {
+				continue
+			}
+			_, cur := mappingEntry(sec, v.name)
+			switch {
+			case v.remove:
+				if cur != nil {
+					removed = append(removed, v.name)
+				}
+			case cur == nil:
+				missing = append(missing, entry{key: v.name, value: v.quantity.String()})
+			case cur.Kind != yaml.ScalarNode:
+				return fmt.Errorf("%s.%s at line %d is not a quantity", section, v.name, cur.Line)
+			default:
+				if q, err := resource.ParseQuantity(cur.Value); err == nil && q.Cmp(v.quantity) == 0 {
+					continue
+				}
+				if err := e.setScalar(cur, v.quantity.String()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(removed) > 0 && len(removed) == len(sec.Content)/2 && len(missing) == 0 {
+			removedSections = append(removedSections, section)
+			continue
+		}
+		if err := e.removeEntries(sec, removed); err != nil {
+			return err
+		}
+		if secKey == nil {
+			if len(missing) > 0 {
+				missingSections = append(missingSections, entry{key: section, children: missing})
+			}
+			continue
+		}
+		if err := e.addEntries(secKey, sec, missing); err != nil {
+			return err
+		}
+	}
+
+	if len(removedSections) > 0 && len(removedSections) == len(res.Content)/2 && len(missingSections) == 0 {
+		return e.removeEntries(container, []string{"resources"})
+	}
+	if err := e.removeEntries(res, removedSections); err != nil {
+		return err
+	}
+	if len(missingSections) == 0 {
+		return nil
+	}
+	if res == nil {
+		if len(container.Content) == 0 {
+			return fmt.Errorf("container at line %d is empty", container.Line)
+		}
+		return e.addEntries(nil, container, []entry{{key: "resources", children: missingSections}})
+	}
+	return e.addEntries(resKey, res, missingSections)
+}
@@ -0,0 +1,986 @@
+package k8s_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNodeResourcePatcher_Patch(t *testing.T) {
+	resCfg := k8s.ResourceConfig{
+		Requests: resourceList("cpu=100m,memory=128Mi"),
+		Limits:   resourceList("cpu=200m,memory=256Mi"),
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		errContains string
+	}{
+		{
+			name: "only changed quantities are rewritten",
+			input: `# Managed by platform team
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  labels: {app: api}
+spec:
+  template:
+    spec:
+      containers:
+        - name: api   # main container
+          image: nginx
+          resources:
+            requests:
+              memory: "64Mi" # bumped in Q3
+              cpu: 100m
+            limits:
+              cpu: '1'
+              memory: 256Mi
+          ports:
+            - containerPort: 80
+`,
+			want: `# Managed by platform team
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  labels: {app: api}
+spec:
+  template:
+    spec:
+      containers:
+        - name: api   # main container
+          image: nginx
+          resources:
+            requests:
+              memory: "128Mi" # bumped in Q3
+              cpu: 100m
+            limits:
+              cpu: '200m'
+              memory: 256Mi
+          ports:
+            - containerPort: 80
+`,
+		},
+		{
+			name: "missing resources block is added",
+			input: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: busybox
+      restartPolicy: Never`,
+			want: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: busybox
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+          limits:
+            cpu: 200m
+            memory: 256Mi
+      restartPolicy: Never`,
+		},
+		{
+			name: "empty resources and missing keys",
+			input: `apiVersion: v1
+kind: Pod
+metadata:
+    name: pod
+spec:
+    containers:
+    - name: app
+      resources: {} # to be sized
+    - name: sidecar
+`,
+			want: `apiVersion: v1
+kind: Pod
+metadata:
+    name: pod
+spec:
+    containers:
+    - name: app
+      resources: # to be sized
+          requests:
+              cpu: 100m
+              memory: 128Mi
+          limits:
+              cpu: 200m
+              memory: 256Mi
+    - name: sidecar
+`,
+		},
+		{
+			name: "flow style resources",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources: {limits: {cpu: "1"}}
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources: {limits: {cpu: "200m", memory: 256Mi}, requests: {cpu: 100m, memory: 128Mi}}
+`,
+		},
+		{
+			name: "semantically equal values are kept",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 0.1
+        memory: 134217728
+      limits:
+        cpu: 200m
+        memory: 0.25Gi
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 0.1
+        memory: 134217728
+      limits:
+        cpu: 200m
+        memory: 0.25Gi
+`,
+		},
+		{
+			name: "multiple documents",
+			input: `kind: Service
+metadata:
+  name: api
+---
+kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 1
+`,
+			want: `kind: Service
+metadata:
+  name: api
+---
+kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 200m
+        memory: 256Mi
+      requests:
+        cpu: 100m
+        memory: 128Mi
+`,
+		},
+		{
+			name: "cronjob",
+			input: `apiVersion: batch/v1
+kind: CronJob
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: report
+            resources:
+              limits: {cpu: 1, memory: 1Gi}
+`,
+			want: `apiVersion: batch/v1
+kind: CronJob
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: report
+            resources:
+              limits: {cpu: 200m, memory: 256Mi}
+              requests:
+                cpu: 100m
+                memory: 128Mi
+`,
+		},
+		{
+			name: "argo rollout",
+			input: `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+spec:
+  strategy:
+    canary:
+      steps:
+      - setWeight: 20
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests: {cpu: 100m, memory: 128Mi}
+          limits: {cpu: 200m, memory: 256Mi}
+`,
+			want: `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+spec:
+  strategy:
+    canary:
+      steps:
+      - setWeight: 20
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests: {cpu: 100m, memory: 128Mi}
+          limits: {cpu: 200m, memory: 256Mi}
+`,
+		},
+		{
+			name:  "argo rollout with workload reference",
+			input: "apiVersion: argoproj.io/v1alpha1\nkind: Rollout\nspec:\n  workloadRef:\n    kind: Deployment\n    name: app\n",
+			want:  "apiVersion: argoproj.io/v1alpha1\nkind: Rollout\nspec:\n  workloadRef:\n    kind: Deployment\n    name: app\n",
+		},
+		{
+			name: "argo rollout with workload reference next to the deployment",
+			input: `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+spec:
+  workloadRef:
+    kind: Deployment
+    name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+`,
+			want: `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+spec:
+  workloadRef:
+    kind: Deployment
+    name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+          limits:
+            cpu: 200m
+            memory: 256Mi
+`,
+		},
+		{
+			name:        "unsupported kind",
+			input:       "apiVersion: v1\nkind: Service\n",
+			errContains: "unsupported kind: Service",
+		},
+		{
+			name:        "invalid yaml",
+			input:       `invalid: [not yaml`,
+			errContains: "YAML unmarshal error",
+		},
+		{
+			name:        "no containers",
+			input:       "kind: Deployment\nspec:\n  template:\n    spec:\n      containers: []\n",
+			errContains: "no containers found",
+		},
+		{
+			name:        "resources is not a mapping",
+			input:       "kind: Pod\nspec:\n  containers:\n  - name: app\n    resources: [cpu]\n",
+			errContains: "resources at line 5 is not a mapping",
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.Patch(t.Context(), []byte(tt.input), resCfg)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchSelectors(t *testing.T) {
+	input := `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+      - name: api
+        image: api
+      - name: worker-1
+        image: worker
+`
+	small := k8s.ContainerConfig{
+		Requests: resourceList("cpu=10m,memory=16Mi"),
+		Limits:   resourceList("cpu=20m,memory=32Mi"),
+	}
+	large := k8s.ContainerConfig{
+		Requests: resourceList("cpu=1,memory=1Gi"),
+		Limits:   resourceList("cpu=2,memory=2Gi"),
+	}
+	with := func(c k8s.ContainerConfig, selector string) k8s.ContainerConfig {
+		c.Selector = selector
+		return c
+	}
+	override := func(c k8s.ContainerConfig, selector string) k8s.ContainerConfig {
+		c.Selector = selector
+		c.Override = true
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		containers  []k8s.ContainerConfig
+		want        string
+		errContains string
+	}{
+		{
+			name:       "exact name and glob",
+			containers: []k8s.ContainerConfig{with(large, "api"), with(small, "worker-*")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+      - name: api
+        image: api
+        resources:
+          requests:
+            cpu: "1"
+            memory: 1Gi
+          limits:
+            cpu: "2"
+            memory: 2Gi
+      - name: worker-1
+        image: worker
+        resources:
+          requests:
+            cpu: 10m
+            memory: 16Mi
+          limits:
+            cpu: 20m
+            memory: 32Mi
+`,
+		},
+		{
+			name:       "first matching selector wins",
+			containers: []k8s.ContainerConfig{with(small, "/^istio/"), with(large, "/.*/")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+        resources:
+          requests:
+            cpu: 10m
+            memory: 16Mi
+          limits:
+            cpu: 20m
+            memory: 32Mi
+      - name: api
+        image: api
+        resources:
+          requests:
+            cpu: "1"
+            memory: 1Gi
+          limits:
+            cpu: "2"
+            memory: 2Gi
+      - name: worker-1
+        image: worker
+        resources:
+          requests:
+            cpu: "1"
+            memory: 1Gi
+          limits:
+            cpu: "2"
+            memory: 2Gi
+`,
+		},
+		{
+			name:       "override keeps the first container selected",
+			containers: []k8s.ContainerConfig{override(small, "worker-*")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+        resources:
+          requests:
+            cpu: 100m
+      - name: api
+        image: api
+      - name: worker-1
+        image: worker
+        resources:
+          requests:
+            cpu: 10m
+            memory: 16Mi
+          limits:
+            cpu: 20m
+            memory: 32Mi
+`,
+		},
+		{
+			name:       "override of the first container",
+			containers: []k8s.ContainerConfig{override(large, "istio-*")},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: istio
+        resources:
+          requests:
+            cpu: "1"
+            memory: 1Gi
+          limits:
+            cpu: "2"
+            memory: 2Gi
+      - name: api
+        image: api
+      - name: worker-1
+        image: worker
+`,
+		},
+		{
+			name:        "selector without match",
+			containers:  []k8s.ContainerConfig{with(large, "api"), with(small, "cron-*")},
+			errContains: `container selector "cron-*" matched no container`,
+		},
+		{
+			name:        "invalid regular expression",
+			containers:  []k8s.ContainerConfig{with(large, "/(/")},
+			errContains: `invalid container selector "/(/"`,
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.Patch(t.Context(), []byte(input), k8s.ResourceConfig{
+				Requests:   resourceList("cpu=100m"),
+				Containers: tt.containers,
+			})
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchWarnings(t *testing.T) {
+	input := "kind: Pod\nspec:\n  containers:\n  - name: app\n  - name: proxy\n"
+
+	var warnings bytes.Buffer
+	_, err := (&k8s.NodeResourcePatcher{}).Patch(t.Context(), []byte(input), k8s.ResourceConfig{
+		Requests: resourceList("cpu=100m"),
+		Warnings: &warnings,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Warning: Multiple containers found in Pod, updating only the first one\n", warnings.String())
+}
+
+func TestNodeResourcePatcher_PatchInitContainers(t *testing.T) {
+	input := `kind: Pod
+spec:
+  initContainers:
+  - name: migrate
+    image: migrate
+  - name: istio-proxy
+    image: istio
+    restartPolicy: Always
+  containers:
+  - name: app
+    image: app
+`
+	values := func(selector string, typ k8s.ContainerType, cpu string) k8s.ContainerConfig {
+		return k8s.ContainerConfig{
+			Selector: selector,
+			Type:     typ,
+			Requests: resourceList("cpu=" + cpu + ",memory=64Mi"),
+			Limits:   resourceList("cpu=" + cpu + ",memory=64Mi"),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		resCfg      k8s.ResourceConfig
+		want        string
+		errContains string
+	}{
+		{
+			name: "init containers and sidecars with their own values",
+			resCfg: k8s.ResourceConfig{
+				Requests: resourceList("cpu=100m,memory=128Mi"),
+				Limits:   resourceList("cpu=200m,memory=256Mi"),
+				Containers: []k8s.ContainerConfig{
+					values("*", k8s.InitContainers, "500m"),
+					values("*", k8s.SidecarContainers, "50m"),
+				},
+			},
+			want: `kind: Pod
+spec:
+  initContainers:
+  - name: migrate
+    image: migrate
+    resources:
+      requests:
+        cpu: 500m
+        memory: 64Mi
+      limits:
+        cpu: 500m
+        memory: 64Mi
+  - name: istio-proxy
+    image: istio
+    restartPolicy: Always
+    resources:
+      requests:
+        cpu: 50m
+        memory: 64Mi
+      limits:
+        cpu: 50m
+        memory: 64Mi
+  containers:
+  - name: app
+    image: app
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+      limits:
+        cpu: 200m
+        memory: 256Mi
+`,
+		},
+		{
+			name: "both init container types",
+			resCfg: k8s.ResourceConfig{
+				Containers: []k8s.ContainerConfig{
+					values("app", k8s.AppContainers, "1"),
+					values("/^(migrate|istio-proxy)$/", k8s.InitContainers|k8s.SidecarContainers, "10m"),
+				},
+			},
+			want: `kind: Pod
+spec:
+  initContainers:
+  - name: migrate
+    image: migrate
+    resources:
+      requests:
+        cpu: 10m
+        memory: 64Mi
+      limits:
+        cpu: 10m
+        memory: 64Mi
+  - name: istio-proxy
+    image: istio
+    restartPolicy: Always
+    resources:
+      requests:
+        cpu: 10m
+        memory: 64Mi
+      limits:
+        cpu: 10m
+        memory: 64Mi
+  containers:
+  - name: app
+    image: app
+    resources:
+      requests:
+        cpu: "1"
+        memory: 64Mi
+      limits:
+        cpu: "1"
+        memory: 64Mi
+`,
+		},
+		{
+			name: "sidecar selector does not match plain init containers",
+			resCfg: k8s.ResourceConfig{
+				Containers: []k8s.ContainerConfig{values("migrate", k8s.SidecarContainers, "10m")},
+			},
+			errContains: `container selector "migrate" matched no container`,
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.Patch(t.Context(), []byte(input), tt.resCfg)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchPartial(t *testing.T) {
+	input := `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+        ephemeral-storage: 1Gi
+      limits:
+        cpu: 200m
+        memory: 256Mi
+`
+
+	tests := []struct {
+		name        string
+		input       string
+		resCfg      k8s.ResourceConfig
+		want        string
+		errContains string
+	}{
+		{
+			name:   "only the configured value changes",
+			input:  input,
+			resCfg: k8s.ResourceConfig{Limits: resourceList("memory=512Mi")},
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+        ephemeral-storage: 1Gi
+      limits:
+        cpu: 200m
+        memory: 512Mi
+`,
+		},
+		{
+			name:   "remove an entry",
+			input:  input,
+			resCfg: k8s.ResourceConfig{Remove: []string{"limits.cpu", "requests.nvidia.com/gpu"}},
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 100m
+        memory: 128Mi
+        ephemeral-storage: 1Gi
+      limits:
+        memory: 256Mi
+`,
+		},
+		{
+			name:   "removing every limit drops the section",
+			input:  input,
+			resCfg: k8s.ResourceConfig{Requests: resourceList("cpu=50m"), Remove: []string{"limits.cpu", "limits.memory"}},
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 50m
+        memory: 128Mi
+        ephemeral-storage: 1Gi
+`,
+		},
+		{
+			name:   "removing every entry drops the resources block",
+			input:  "kind: Pod\nspec:\n  containers:\n  - name: app\n    resources:\n      limits:\n        cpu: 200m\n    image: nginx\n",
+			resCfg: k8s.ResourceConfig{Remove: []string{"limits.cpu"}},
+			want:   "kind: Pod\nspec:\n  containers:\n  - name: app\n    image: nginx\n",
+		},
+		{
+			name:   "remove from flow mapping",
+			input:  "kind: Pod\nspec:\n  containers:\n  - name: app\n    resources: {limits: {cpu: 200m, memory: 256Mi}, requests: {cpu: 100m}}\n",
+			resCfg: k8s.ResourceConfig{Remove: []string{"limits.cpu"}},
+			want:   "kind: Pod\nspec:\n  containers:\n  - name: app\n    resources: {limits: {memory: 256Mi}, requests: {cpu: 100m}}\n",
+		},
+		{
+			name:        "set and removed",
+			input:       input,
+			resCfg:      k8s.ResourceConfig{Limits: resourceList("cpu=1"), Remove: []string{"limits.cpu"}},
+			errContains: "resource limits.cpu is both set and removed",
+		},
+		{
+			name:        "invalid removal key",
+			input:       input,
+			resCfg:      k8s.ResourceConfig{Remove: []string{"cpu"}},
+			errContains: `invalid resource key "cpu"`,
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.Patch(t.Context(), []byte(tt.input), tt.resCfg)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchMultilineValues(t *testing.T) {
+	resCfg := k8s.ResourceConfig{Requests: resourceList("cpu=100m")}
+
+	tests := []struct {
+		name   string
+		input  string
+		resCfg k8s.ResourceConfig
+		want   string
+	}{
+		{
+			name: "after a literal script in a command",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    command:
+    - sh
+    - -c
+    - |
+      echo start
+
+      exec app
+  - name: sidecar
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    command:
+    - sh
+    - -c
+    - |
+      echo start
+
+      exec app
+    resources:
+      requests:
+        cpu: 100m
+  - name: sidecar
+`,
+		},
+		{
+			name: "after a literal env value kept with trailing lines",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    env:
+    - name: CONFIG
+      value: |+
+        key: value
+        # not a comment
+
+  - name: sidecar
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    env:
+    - name: CONFIG
+      value: |+
+        key: value
+        # not a comment
+
+    resources:
+      requests:
+        cpu: 100m
+  - name: sidecar
+`,
+		},
+		{
+			name: "after a multi-line flow sequence",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    args: [
+      --port=8080,
+      --verbose
+    ]
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    args: [
+      --port=8080,
+      --verbose
+    ]
+    resources:
+      requests:
+        cpu: 100m
+`,
+		},
+		{
+			name: "after a plain scalar continued on the next line",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 200m
+    description: runs the
+      main process
+`,
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 200m
+      requests:
+        cpu: 100m
+    description: runs the
+      main process
+`,
+		},
+		{
+			name: "removing a section ending in a folded scalar",
+			input: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: >-
+          200m
+      requests:
+        cpu: 50m
+    command: [app]
+`,
+			resCfg: k8s.ResourceConfig{Requests: resourceList("cpu=100m"), Remove: []string{"limits.cpu"}},
+			want: `kind: Pod
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 100m
+    command: [app]
+`,
+		},
+	}
+
+	patcher := &k8s.NodeResourcePatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := resCfg
+			if tt.resCfg.Requests != nil {
+				cfg = tt.resCfg
+			}
+			got, err := patcher.Patch(t.Context(), []byte(tt.input), cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+
+			// Apart from resources, the output must decode to the input.
+			var in, out map[string]any
+			require.NoError(t, yaml.Unmarshal([]byte(tt.input), &in))
+			require.NoError(t, yaml.Unmarshal(got, &out))
+			for _, doc := range []map[string]any{in, out} {
+				for _, c := range doc["spec"].(map[string]any)["containers"].([]any) {
+					delete(c.(map[string]any), "resources")
+				}
+			}
+			assert.Equal(t, in, out)
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := (&k8s.NodeResourcePatcher{}).Patch(ctx, []byte("kind: Pod\nspec:\n  containers:\n  - name: app\n"), k8s.ResourceConfig{
+		Requests: resourceList("cpu=100m"),
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
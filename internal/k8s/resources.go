@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseResourceList parses resource quantities in the form
+//
+//	name=quantity[,name=quantity...]
+//
+// e.g. "cpu=100m,memory=128Mi,nvidia.com/gpu=1". An empty string yields a nil
+// list.
+func ParseResourceList(s string) (corev1.ResourceList, error) {
+	var list corev1.ResourceList
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid resource %q: expected <name>=<quantity>", item)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid resource name %q: %s", name, strings.Join(errs, "; "))
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q for %s: %w", value, name, err)
+		}
+		if _, ok := list[corev1.ResourceName(name)]; ok {
+			return nil, fmt.Errorf("duplicate resource %s", name)
+		}
+		if list == nil {
+			list = corev1.ResourceList{}
+		}
+		list[corev1.ResourceName(name)] = q
+	}
+	return list, nil
+}
+
+// Validate checks the quantities, removals, adjustments and rounding of
+// resCfg and of each of its container configs.
+func (resCfg ResourceConfig) Validate() error {
+	top := ContainerConfig{Requests: resCfg.Requests, Limits: resCfg.Limits, Remove: resCfg.Remove, Adjustments: resCfg.Adjustments}
+	if _, err := top.values(); err != nil {
+		return err
+	}
+	for name, unit := range resCfg.Rounding {
+		if unit.Sign() <= 0 {
+			return fmt.Errorf("rounding unit of %s must be positive", name)
+		}
+	}
+	for _, c := range resCfg.Containers {
+		if _, err := compileSelector(c.Selector); err != nil {
+			return err
+		}
+		if _, err := c.values(); err != nil {
+			return fmt.Errorf("container selector %q: %w", c.Selector, err)
+		}
+	}
+	return nil
+}
+
+// RequiresEqualLimit reports whether Kubernetes requires the request of the
+// resource to equal its limit, which is the case for extended resources such
+// as nvidia.com/gpu and for huge pages.
+func RequiresEqualLimit(name corev1.ResourceName) bool {
+	s := string(name)
+	if strings.HasPrefix(s, corev1.ResourceHugePagesPrefix) {
+		return true
+	}
+	return strings.Contains(s, "/") &&
+		!strings.HasPrefix(s, corev1.ResourceDefaultNamespacePrefix) &&
+		!strings.HasPrefix(s, corev1.DefaultResourceRequestsPrefix)
+}
+
+// validateResources checks that every extended resource or huge page size
+// with a request also has an equal limit.
+func validateResources(requests, limits corev1.ResourceList) error {
+	for _, name := range ResourceNames(requests) {
+		if !RequiresEqualLimit(name) {
+			continue
+		}
+		req := requests[name]
+		limit, ok := limits[name]
+		if !ok {
+			return fmt.Errorf("resource %s: a request requires an equal limit", name)
+		}
+		if req.Cmp(limit) != 0 {
+			return fmt.Errorf("resource %s: request %s must equal limit %s", name, req.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+// ResourceNames returns the names found in any of lists, in alphabetical
+// order.
+func ResourceNames(lists ...corev1.ResourceList) []corev1.ResourceName {
+	seen := map[corev1.ResourceName]bool{}
+	var names []corev1.ResourceName
+	for _, list := range lists {
+		for name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,176 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"k8s-resource-adjustment/internal/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseResourceList(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        corev1.ResourceList
+		errContains string
+	}{
+		{
+			name:  "empty",
+			input: " ",
+		},
+		{
+			name:  "standard and extended resources",
+			input: "cpu=100m, memory=128Mi,ephemeral-storage=1Gi,hugepages-2Mi=64Mi,nvidia.com/gpu=1",
+			want: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("100m"),
+				corev1.ResourceMemory:           resource.MustParse("128Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				"hugepages-2Mi":                 resource.MustParse("64Mi"),
+				"nvidia.com/gpu":                resource.MustParse("1"),
+			},
+		},
+		{
+			name:        "missing quantity",
+			input:       "cpu",
+			errContains: `invalid resource "cpu": expected <name>=<quantity>`,
+		},
+		{
+			name:        "invalid quantity",
+			input:       "memory=lots",
+			errContains: `invalid quantity "lots" for memory`,
+		},
+		{
+			name:        "invalid name",
+			input:       "nvidia.com/gpu/a100=1",
+			errContains: `invalid resource name "nvidia.com/gpu/a100"`,
+		},
+		{
+			name:        "duplicate",
+			input:       "cpu=1,cpu=2",
+			errContains: "duplicate resource cpu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k8s.ParseResourceList(tt.input)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	requests := corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+	}
+	limits := corev1.ResourceList{
+		"nvidia.com/gpu":      resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+
+	assert.Empty(t, k8s.ResourceNames())
+	assert.Empty(t, k8s.ResourceNames(nil, corev1.ResourceList{}))
+	assert.Equal(t, []corev1.ResourceName{"cpu", "memory"}, k8s.ResourceNames(requests))
+	assert.Equal(t, []corev1.ResourceName{"cpu", "memory", "nvidia.com/gpu"}, k8s.ResourceNames(requests, limits))
+}
+
+func TestResourceConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		resCfg      k8s.ResourceConfig
+		errContains string
+	}{
+		{
+			name: "extended resources with equal request and limit",
+			resCfg: k8s.ResourceConfig{
+				Requests: resourceList("cpu=100m,nvidia.com/gpu=1,hugepages-2Mi=64Mi"),
+				Limits:   resourceList("cpu=1,nvidia.com/gpu=1,hugepages-2Mi=64Mi"),
+			},
+		},
+		{
+			name:   "extended resource with limit only",
+			resCfg: k8s.ResourceConfig{Limits: resourceList("nvidia.com/gpu=2")},
+		},
+		{
+			name: "extended resource request differs from limit",
+			resCfg: k8s.ResourceConfig{
+				Requests: resourceList("nvidia.com/gpu=1"),
+				Limits:   resourceList("nvidia.com/gpu=2"),
+			},
+			errContains: "resource nvidia.com/gpu: request 1 must equal limit 2",
+		},
+		{
+			name:        "extended resource request without limit",
+			resCfg:      k8s.ResourceConfig{Requests: resourceList("nvidia.com/gpu=1")},
+			errContains: "resource nvidia.com/gpu: a request requires an equal limit",
+		},
+		{
+			name: "huge pages in a container config",
+			resCfg: k8s.ResourceConfig{Containers: []k8s.ContainerConfig{{
+				Selector: "app",
+				Requests: resourceList("hugepages-2Mi=64Mi"),
+				Limits:   resourceList("hugepages-2Mi=128Mi"),
+			}}},
+			errContains: `container selector "app": resource hugepages-2Mi: request 64Mi must equal limit 128Mi`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.resCfg.Validate()
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNodeResourcePatcher_PatchExtendedResources(t *testing.T) {
+	input := `kind: Pod
+spec:
+  containers:
+  - name: train
+    resources:
+      requests:
+        cpu: 1
+      limits:
+        cpu: 2
+`
+	resCfg := k8s.ResourceConfig{
+		Requests: resourceList("ephemeral-storage=10Gi,nvidia.com/gpu=1"),
+		Limits:   resourceList("ephemeral-storage=20Gi,nvidia.com/gpu=1"),
+	}
+
+	got, err := (&k8s.NodeResourcePatcher{}).Patch(t.Context(), []byte(input), resCfg)
+	require.NoError(t, err)
+	assert.Equal(t, `kind: Pod
+spec:
+  containers:
+  - name: train
+    resources:
+      requests:
+        cpu: 1
+        ephemeral-storage: 10Gi
+        nvidia.com/gpu: "1"
+      limits:
+        cpu: 2
+        ephemeral-storage: 20Gi
+        nvidia.com/gpu: "1"
+`, string(got))
+
+	resCfg.Limits = resourceList("nvidia.com/gpu=2")
+	_, err = (&k8s.NodeResourcePatcher{}).Patch(t.Context(), []byte(input), resCfg)
+	assert.ErrorContains(t, err, "request 1 must equal limit 2")
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// containerMatcher reports whether a container name is matched by a selector.
+type containerMatcher func(name string) bool
+
+func compileSelector(selector string) (containerMatcher, error) {
+	switch {
+	case selector == "":
+		return nil, fmt.Errorf("empty container selector")
+	case len(selector) > 1 && strings.HasPrefix(selector, "/") && strings.HasSuffix(selector, "/"):
+		re, err := regexp.Compile(selector[1 : len(selector)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid container selector %q: %w", selector, err)
+		}
+		return re.MatchString, nil
+	case strings.ContainsAny(selector, "*?["):
+		if _, err := path.Match(selector, ""); err != nil {
+			return nil, fmt.Errorf("invalid container selector %q: %w", selector, err)
+		}
+		return func(name string) bool {
+			ok, _ := path.Match(selector, name)
+			return ok
+		}, nil
+	default:
+		return func(name string) bool { return name == selector }, nil
+	}
+}
+
+// containerRef identifies a container of a pod spec for selection.
+type containerRef struct {
+	name string
+	typ  ContainerType
+}
+
+// containerSelection is a container, by index, and the resources to apply.
+type containerSelection struct {
+	index int
+	cfg   ContainerConfig
+}
+
+// effectiveType returns the container types c applies to.
+func (c ContainerConfig) effectiveType() ContainerType {
+	if c.Type == 0 {
+		return AppContainers
+	}
+	return c.Type
+}
+
+// selectContainers decides which of the referenced containers are patched.
+// Each container is assigned the first config whose type and selector match
+// it; matched records the index of every config that matched. When no config
+// other than overrides targets app containers, the first app container
+// receives the top-level values of resCfg unless an override matches it.
+func selectContainers(kind string, refs []containerRef, resCfg ResourceConfig, matched map[int]bool) ([]containerSelection, error) {
+	matchers := make([]containerMatcher, len(resCfg.Containers))
+	selectsApps := false
+	for i, c := range resCfg.Containers {
+		m, err := compileSelector(c.Selector)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+		selectsApps = selectsApps || !c.Override && c.effectiveType()&AppContainers != 0
+	}
+
+	first := -1
+	if !selectsApps {
+		var apps []int
+		for i, ref := range refs {
+			if ref.typ == AppContainers {
+				apps = append(apps, i)
+			}
+		}
+		if len(apps) == 0 {
+			return nil, fmt.Errorf("no containers found in %s", kind)
+		}
+		if len(apps) > 1 {
+			if resCfg.Warnings != nil {
+				fmt.Fprintf(resCfg.Warnings, "Warning: Multiple containers found in %s, updating only the first one\n", kind)
+			}
+		}
+		first = apps[0]
+	}
+
+	var selected []containerSelection
+	for idx, ref := range refs {
+		sel := containerSelection{index: -1}
+		if idx == first {
+			sel = containerSelection{index: idx, cfg: ContainerConfig{
+				Requests:    resCfg.Requests,
+				Limits:      resCfg.Limits,
+				Remove:      resCfg.Remove,
+				Adjustments: resCfg.Adjustments,
+			}}
+		}
+		for i, match := range matchers {
+			if resCfg.Containers[i].effectiveType()&ref.typ != 0 && match(ref.name) {
+				matched[i] = true
+				sel = containerSelection{index: idx, cfg: resCfg.Containers[i]}
+				break
+			}
+		}
+		if sel.index >= 0 {
+			selected = append(selected, sel)
+		}
+	}
+	return selected, nil
+}
+
+// unmatchedSelectors returns an error naming every container config of resCfg
+// that did not match any container.
+func unmatchedSelectors(resCfg ResourceConfig, matched map[int]bool) error {
+	var missing []string
+	for i, c := range resCfg.Containers {
+		if !matched[i] {
+			missing = append(missing, fmt.Sprintf("%q", c.Selector))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("container selector %s matched no container", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package pool
+
+import "sync"
+
+// Run calls fn for every item, with at most workers calls running at once,
+// and returns the results in the order of items. If done is not nil, it is
+// called with the index and result of every item as soon as it completes,
+// one at a time from the calling goroutine, so that it needs no locking.
+// Fewer than one worker means one.
+func Run[T, R any](workers int, items []T, fn func(T) R, done func(int, R)) []R {
+	if workers < 1 {
+		workers = 1
+	}
+	type result struct {
+		index int
+		value R
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- result{i, fn(items[i])}
+			}
+		}()
+	}
+	go func() {
+		for i := range items {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	values := make([]R, len(items))
+	for r := range results {
+		values[r.index] = r.value
+		if done != nil {
+			done(r.index, r.value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,38 @@
+package pool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/pool"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var running, peak atomic.Int32
+	square := func(n int) int {
+		r := running.Add(1)
+		for p := peak.Load(); r > p && !peak.CompareAndSwap(p, r); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return n * n
+	}
+
+	var done []int
+	got := pool.Run(3, []int{1, 2, 3, 4, 5, 6, 7}, square, func(i, r int) {
+		done = append(done, i)
+	})
+
+	assert.Equal(t, []int{1, 4, 9, 16, 25, 36, 49}, got)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6}, done)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Greater(t, peak.Load(), int32(1))
+}
+
+func TestRun_Edges(t *testing.T) {
+	assert.Empty(t, pool.Run(4, nil, func(n int) int { return n }, nil))
+	assert.Equal(t, []int{2, 4}, pool.Run(0, []int{1, 2}, func(n int) int { return 2 * n }, nil))
+}
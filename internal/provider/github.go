@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHubProvider implements Provider with GitHub repositories and pull
+// requests, using the GitHub REST API.
+type GitHubProvider struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitHubProvider returns a provider for the GitHub API at baseURL, e.g.
+// "https://github.example.com/api/v3" for GitHub Enterprise Server. An empty
+// baseURL selects https://api.github.com.
+func NewGitHubProvider(baseURL, token string) *GitHubProvider {
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return &GitHubProvider{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: http.DefaultClient}
+}
+
+// gitHubError is returned for responses with an error status.
+type gitHubError struct {
+	method, path string
+	status       int
+	Message      string `json:"message"`
+}
+
+func (e *gitHubError) Error() string {
+	return fmt.Sprintf("GitHub API %s %s: %d %s", e.method, e.path, e.status, e.Message)
+}
+
+// do sends a request to path and decodes the JSON response into out. It
+// returns the response headers.
+func (p *GitHubProvider) do(ctx context.Context, method, path string, in, out any) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &gitHubError{method: method, path: path, status: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		return nil, apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("GitHub API %s %s: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// ListRepositories returns the full names of the repositories of an
+// organisation or, if no such organisation exists, of a user.
+func (p *GitHubProvider) ListRepositories(ctx context.Context, owner string) ([]string, error) {
+	repos, err := p.listRepositories(ctx, "/orgs/"+url.PathEscape(owner)+"/repos")
+	var apiErr *gitHubError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		repos, err = p.listRepositories(ctx, "/users/"+url.PathEscape(owner)+"/repos")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	return repos, nil
+}
+
+func (p *GitHubProvider) listRepositories(ctx context.Context, path string) ([]string, error) {
+	var repos []string
+	for page := 1; ; page++ {
+		var batch []struct {
+			FullName string `json:"full_name"`
+		}
+		header, err := p.do(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", path, page), nil, &batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range batch {
+			repos = append(repos, r.FullName)
+		}
+		if !strings.Contains(header.Get("Link"), `rel="next"`) {
+			return repos, nil
+		}
+	}
+}
+
+// OpenChangeRequest opens a pull request, then applies its labels and
+// assignees, and returns its web URL.
+func (p *GitHubProvider) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	repo := repositoryPath(cr.Repository)
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	_, err := p.do(ctx, http.MethodPost, "/repos/"+repo+"/pulls", map[string]string{
+		"title": cr.Title,
+		"body":  cr.Description,
+		"head":  cr.SourceBranch,
+		"base":  cr.TargetBranch,
+	}, &pr)
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request: %w", err)
+	}
+
+	issue := fmt.Sprintf("/repos/%s/issues/%d", repo, pr.Number)
+	if len(cr.Labels) > 0 {
+		if _, err := p.do(ctx, http.MethodPost, issue+"/labels", map[string][]string{"labels": cr.Labels}, nil); err != nil {
+			return pr.HTMLURL, fmt.Errorf("failed to label pull request %s: %w", pr.HTMLURL, err)
+		}
+	}
+	if len(cr.Assignees) > 0 {
+		if _, err := p.do(ctx, http.MethodPost, issue+"/assignees", map[string][]string{"assignees": cr.Assignees}, nil); err != nil {
+			return pr.HTMLURL, fmt.Errorf("failed to assign pull request %s: %w", pr.HTMLURL, err)
+		}
+	}
+	return pr.HTMLURL, nil
+}
@@ -0,0 +1,131 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub serves the subset of the GitHub REST API used by
+// GitHubProvider and records the request bodies by path.
+func fakeGitHub(t *testing.T, requests map[string]map[string]any) *httptest.Server {
+	record := func(r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests[r.URL.Path] = body
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/platform/repos", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/platform/repos?page=2>; rel="next"`, "http://"+r.Host))
+			w.Write([]byte(`[{"full_name": "platform/api"}, {"full_name": "platform/web"}]`))
+			return
+		}
+		w.Write([]byte(`[{"full_name": "platform/worker"}]`))
+	})
+	mux.HandleFunc("GET /orgs/octocat/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+	mux.HandleFunc("GET /users/octocat/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"full_name": "octocat/hello-world"}]`))
+	})
+	mux.HandleFunc("POST /repos/platform/api/pulls", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 42, "html_url": "https://github.com/platform/api/pull/42"}`))
+	})
+	mux.HandleFunc("POST /repos/platform/api/issues/42/{field}", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /repos/platform/closed/pulls", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitHubProvider_ListRepositories(t *testing.T) {
+	server := fakeGitHub(t, map[string]map[string]any{})
+	p := provider.NewGitHubProvider(server.URL, "secret")
+
+	repos, err := p.ListRepositories(t.Context(), "platform")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"platform/api", "platform/web", "platform/worker"}, repos)
+
+	repos, err = p.ListRepositories(t.Context(), "octocat")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"octocat/hello-world"}, repos)
+}
+
+func TestGitHubProvider_OpenChangeRequest(t *testing.T) {
+	requests := map[string]map[string]any{}
+	server := fakeGitHub(t, requests)
+	p := provider.NewGitHubProvider(server.URL, "secret")
+
+	url, err := p.OpenChangeRequest(t.Context(), provider.ChangeRequest{
+		Repository:   "platform/api",
+		SourceBranch: "resource-adjustment/prod",
+		TargetBranch: "main",
+		Title:        "Adjust resources",
+		Description:  "Diff summary",
+		Labels:       []string{"automation"},
+		Assignees:    []string{"alice"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/platform/api/pull/42", url)
+	assert.Equal(t, map[string]any{
+		"title": "Adjust resources",
+		"body":  "Diff summary",
+		"head":  "resource-adjustment/prod",
+		"base":  "main",
+	}, requests["/repos/platform/api/pulls"])
+	assert.Equal(t, map[string]any{"labels": []any{"automation"}}, requests["/repos/platform/api/issues/42/labels"])
+	assert.Equal(t, map[string]any{"assignees": []any{"alice"}}, requests["/repos/platform/api/issues/42/assignees"])
+
+	_, err = p.OpenChangeRequest(t.Context(), provider.ChangeRequest{Repository: "https://github.com/platform/closed.git"})
+	assert.ErrorContains(t, err, "GitHub API POST /repos/platform/closed/pulls: 422 Validation Failed")
+}
+
+func TestNew(t *testing.T) {
+	p, err := provider.New("github", "", "token")
+	require.NoError(t, err)
+	assert.IsType(t, &provider.GitHubProvider{}, p)
+
+	p, err = provider.New("gitlab", "", "token")
+	require.NoError(t, err)
+	assert.IsType(t, &provider.GitLabProvider{}, p)
+
+	_, err = provider.New("bitbucket", "", "token")
+	assert.ErrorContains(t, err, `unknown provider "bitbucket"`)
+}
+
+func TestGitHubProvider_OpenChangeRequestTimeout(t *testing.T) {
+	// The server hangs until the test ends.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := provider.NewGitHubProvider(server.URL, "secret").OpenChangeRequest(ctx, provider.ChangeRequest{Repository: "platform/api"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
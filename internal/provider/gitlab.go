@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GitLabProvider implements Provider with GitLab projects and merge requests.
+type GitLabProvider struct {
+	client *gitlab.Client
+}
+
+// NewGitLabProvider returns a provider for the GitLab instance at baseURL,
+// e.g. "https://gitlab.com", authenticated with a personal access token.
+func NewGitLabProvider(baseURL, token string) (*GitLabProvider, error) {
+	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(baseURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	return &GitLabProvider{client: client}, nil
+}
+
+// OpenChangeRequest opens a merge request and returns its web URL.
+func (p *GitLabProvider) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	opt := &gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.Ptr(cr.Title),
+		Description:        gitlab.Ptr(cr.Description),
+		SourceBranch:       gitlab.Ptr(cr.SourceBranch),
+		TargetBranch:       gitlab.Ptr(cr.TargetBranch),
+		RemoveSourceBranch: gitlab.Ptr(true),
+	}
+	if len(cr.Labels) > 0 {
+		labels := gitlab.LabelOptions(cr.Labels)
+		opt.Labels = &labels
+	}
+	if len(cr.Assignees) > 0 {
+		ids, err := p.userIDs(ctx, cr.Assignees)
+		if err != nil {
+			return "", err
+		}
+		opt.AssigneeIDs = &ids
+	}
+
+	mr, _, err := p.client.MergeRequests.CreateMergeRequest(repositoryPath(cr.Repository), opt, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// ListRepositories returns the paths of the projects of a group.
+func (p *GitLabProvider) ListRepositories(ctx context.Context, group string) ([]string, error) {
+	opt := &gitlab.ListGroupProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var repos []string
+	for {
+		projects, resp, err := p.client.Groups.ListGroupProjects(group, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects: %w", err)
+		}
+		for _, project := range projects {
+			repos = append(repos, project.PathWithNamespace)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return repos, nil
+}
+
+// userIDs resolves usernames to GitLab user IDs.
+func (p *GitLabProvider) userIDs(ctx context.Context, usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		users, _, err := p.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
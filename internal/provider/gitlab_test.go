@@ -0,0 +1,125 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitLab serves the subset of the GitLab REST API used by
+// GitLabProvider and records the merge requests created.
+func fakeGitLab(t *testing.T, created *[]map[string]any) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+		users := map[string]int{"alice": 7, "bob": 8}
+		id, ok := users[r.URL.Query().Get("username")]
+		if !ok {
+			w.Write([]byte(`[]`))
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]any{{"id": id, "username": r.URL.Query().Get("username")}})
+	})
+	mux.HandleFunc("GET /api/v4/groups/42/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": 1, "path_with_namespace": "platform/api"}]`))
+			return
+		}
+		w.Write([]byte(`[{"id": 2, "path_with_namespace": "platform/web"}]`))
+	})
+	mux.HandleFunc("POST /api/v4/projects/{project}/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		assert.Equal(t, "/api/v4/projects/platform%2Fapi/merge_requests", r.URL.EscapedPath())
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*created = append(*created, body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 100, "iid": 1, "web_url": "https://gitlab.example.com/platform/api/-/merge_requests/1"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitLabProvider_OpenChangeRequest(t *testing.T) {
+	var created []map[string]any
+	server := fakeGitLab(t, &created)
+
+	p, err := provider.NewGitLabProvider(server.URL, "secret")
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		created = nil
+		url, err := p.OpenChangeRequest(t.Context(), provider.ChangeRequest{
+			Repository:   "platform/api.git",
+			SourceBranch: "resource-adjustment/prod",
+			TargetBranch: "main",
+			Title:        "Adjust resources",
+			Description:  "Diff summary",
+			Labels:       []string{"automation", "resources"},
+			Assignees:    []string{"alice", "bob"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://gitlab.example.com/platform/api/-/merge_requests/1", url)
+		require.Len(t, created, 1)
+		assert.Equal(t, "Adjust resources", created[0]["title"])
+		assert.Equal(t, "Diff summary", created[0]["description"])
+		assert.Equal(t, "resource-adjustment/prod", created[0]["source_branch"])
+		assert.Equal(t, "main", created[0]["target_branch"])
+		assert.Equal(t, "automation,resources", created[0]["labels"])
+		assert.Equal(t, []any{7.0, 8.0}, created[0]["assignee_ids"])
+	})
+
+	t.Run("unknown assignee", func(t *testing.T) {
+		created = nil
+		_, err := p.OpenChangeRequest(t.Context(), provider.ChangeRequest{
+			Repository: "platform/api",
+			Assignees:  []string{"mallory"},
+		})
+		assert.ErrorContains(t, err, "user mallory not found")
+		assert.Empty(t, created)
+	})
+
+	t.Run("project URL", func(t *testing.T) {
+		created = nil
+		_, err := p.OpenChangeRequest(t.Context(), provider.ChangeRequest{Repository: "https://gitlab.example.com/platform/api.git"})
+		require.NoError(t, err)
+		assert.Len(t, created, 1)
+	})
+}
+
+func TestGitLabProvider_ListRepositories(t *testing.T) {
+	server := fakeGitLab(t, nil)
+
+	p, err := provider.NewGitLabProvider(server.URL, "secret")
+	require.NoError(t, err)
+
+	repos, err := p.ListRepositories(t.Context(), "42")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"platform/api", "platform/web"}, repos)
+}
+
+func TestGitLabProvider_OpenChangeRequestTimeout(t *testing.T) {
+	// The server hangs until the test ends.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	p, err := provider.NewGitLabProvider(server.URL, "secret")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.OpenChangeRequest(ctx, provider.ChangeRequest{Repository: "platform/api"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ChangeRequest describes a merge or pull request to open for a pushed
+// branch.
+type ChangeRequest struct {
+	// Repository is the path of the repository, e.g. "group/service".
+	Repository   string
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	Description  string
+	Labels       []string
+	// Assignees are usernames.
+	Assignees []string
+}
+
+// ChangeRequestOpener opens change requests on a Git hosting provider.
+type ChangeRequestOpener interface {
+	// OpenChangeRequest opens cr and returns its web URL.
+	OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error)
+}
+
+// RepositoryLister lists the repositories of a group or organisation.
+type RepositoryLister interface {
+	// ListRepositories returns the paths of the repositories of group,
+	// e.g. "group/service", as used in REPO_URLS.
+	ListRepositories(ctx context.Context, group string) ([]string, error)
+}
+
+// Provider is a Git hosting provider such as GitLab or GitHub.
+type Provider interface {
+	RepositoryLister
+	ChangeRequestOpener
+}
+
+// New returns the provider named name, "gitlab" or "github", for the
+// instance at baseURL. An empty baseURL selects the public instance.
+func New(name, baseURL, token string) (Provider, error) {
+	switch name {
+	case "gitlab", "":
+		if baseURL == "" {
+			baseURL = "https://gitlab.com"
+		}
+		return NewGitLabProvider(baseURL, token)
+	case "github":
+		return NewGitHubProvider(baseURL, token), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q: expected gitlab or github", name)
+	}
+}
+
+// repositoryPath returns the path of a repository URL or path, e.g.
+// "group/service" for "https://gitlab.com/group/service.git".
+func repositoryPath(repository string) string {
+	path := strings.TrimSuffix(repository, ".git")
+	if _, rest, ok := strings.Cut(path, "://"); ok {
+		if _, p, ok := strings.Cut(rest, "/"); ok {
+			path = p
+		}
+	}
+	return strings.Trim(path, "/")
+}
@@ -0,0 +1,256 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s-resource-adjustment/internal/commitmsg"
+)
+
+// Status is the result of processing a repository.
+type Status string
+
+const (
+	StatusUpdated     Status = "updated"
+	StatusUnchanged   Status = "unchanged"
+	StatusWouldChange Status = "would_change"
+	StatusSkipped     Status = "skipped"
+	StatusFailed      Status = "failed"
+	StatusCanceled    Status = "canceled"
+)
+
+// statuses lists the statuses in the order they are counted in.
+var statuses = []Status{StatusUpdated, StatusWouldChange, StatusUnchanged, StatusSkipped, StatusFailed, StatusCanceled}
+
+// Category is the step at which processing a repository stopped, for
+// repositories that were skipped, failed or canceled.
+type Category string
+
+const (
+	CategoryClone        Category = "clone"
+	CategoryRead         Category = "read"
+	CategoryPatch        Category = "patch"
+	CategoryGuardrail    Category = "guardrail"
+	CategoryDiff         Category = "diff"
+	CategoryRender       Category = "render"
+	CategoryWrite        Category = "write"
+	CategoryPush         Category = "push"
+	CategoryMergeRequest Category = "merge_request"
+	CategoryTimeout      Category = "timeout"
+	CategoryCanceled     Category = "canceled"
+)
+
+// Repository is the result of processing a single repository.
+type Repository struct {
+	Repository   string             `json:"repository"`
+	Status       Status             `json:"status"`
+	Category     Category           `json:"category,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Commit       string             `json:"commit,omitempty"`
+	MergeRequest string             `json:"mergeRequest,omitempty"`
+	Changes      []commitmsg.Change `json:"changes,omitempty"`
+	// Duration is written in seconds.
+	Duration time.Duration `json:"-"`
+	// Output is the log of the repository. It is only written to JUnit
+	// reports.
+	Output string `json:"-"`
+}
+
+// MarshalJSON writes the duration of r in seconds.
+func (r Repository) MarshalJSON() ([]byte, error) {
+	type repository Repository
+	return json.Marshal(struct {
+		repository
+		Duration float64 `json:"duration"`
+	}{repository(r), r.Duration.Seconds()})
+}
+
+// Report is the result of a run.
+type Report struct {
+	RunID        string        `json:"runId"`
+	Env          string        `json:"env"`
+	DryRun       bool          `json:"dryRun"`
+	Started      time.Time     `json:"started"`
+	Duration     time.Duration `json:"-"`
+	Repositories []Repository  `json:"repositories"`
+}
+
+// MarshalJSON writes the duration of r in seconds.
+func (r Report) MarshalJSON() ([]byte, error) {
+	type report Report
+	return json.Marshal(struct {
+		report
+		Duration float64 `json:"duration"`
+	}{report(r), r.Duration.Seconds()})
+}
+
+// Counts returns the number of repositories per status.
+func (r *Report) Counts() map[Status]int {
+	counts := map[Status]int{}
+	for _, repo := range r.Repositories {
+		counts[repo.Status]++
+	}
+	return counts
+}
+
+// WriteJSON writes r as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes r as a Markdown table with a row per repository.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Resource adjustment %s\n\n", r.RunID)
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run)"
+	}
+	counts := r.Counts()
+	var parts []string
+	for _, s := range statuses {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], strings.ReplaceAll(string(s), "_", " ")))
+		}
+	}
+	fmt.Fprintf(&b, "Environment `%s`%s: %d repositories: %s.\n\n", r.Env, mode, len(r.Repositories), strings.Join(parts, ", "))
+
+	b.WriteString("| Repository | Status | Changes | Commit | Duration | Error |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, repo := range r.Repositories {
+		var changes []string
+		for _, c := range repo.Changes {
+			for _, v := range c.Values {
+				changes = append(changes, fmt.Sprintf("%s/%s %s %s: %s -> %s", c.Kind, c.Name, c.Container, v.Field, orUnset(v.Old), orUnset(v.New)))
+			}
+		}
+		commit := ""
+		if repo.Commit != "" {
+			commit = "`" + shortHash(repo.Commit) + "`"
+		}
+		if repo.MergeRequest != "" {
+			commit = strings.TrimSpace(commit + " [merge request](" + repo.MergeRequest + ")")
+		}
+		errText := ""
+		if repo.Error != "" {
+			errText = string(repo.Category) + ": " + repo.Error
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(repo.Repository),
+			strings.ReplaceAll(string(repo.Status), "_", " "),
+			markdownCell(strings.Join(changes, "\n")),
+			commit,
+			repo.Duration.Round(time.Millisecond),
+			markdownCell(errText))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return "unset"
+	}
+	return value
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// markdownCell escapes s for use in a table cell, where line breaks become
+// <br>.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+// junitOutput is written as CDATA to keep the line breaks of the output.
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+// WriteJUnit writes r as JUnit XML with a test case per repository. Failed
+// repositories and repositories skipped by a guardrail are failures, and
+// canceled repositories are skipped.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      "resource-adjustment." + r.Env,
+		Tests:     len(r.Repositories),
+		Time:      seconds(r.Duration),
+		Timestamp: r.Started.UTC().Format(time.RFC3339),
+	}
+	for _, repo := range r.Repositories {
+		c := junitTestCase{
+			Name:      repo.Repository,
+			Classname: suite.Name,
+			Time:      seconds(repo.Duration),
+		}
+		if repo.Output != "" {
+			c.SystemOut = &junitOutput{repo.Output}
+		}
+		message := &junitMessage{Message: repo.Error, Type: string(repo.Category)}
+		switch repo.Status {
+		case StatusFailed, StatusSkipped:
+			suite.Failures++
+			c.Failure = message
+		case StatusCanceled:
+			suite.Skipped++
+			c.Skipped = message
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
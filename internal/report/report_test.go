@@ -0,0 +1,156 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"k8s-resource-adjustment/internal/commitmsg"
+	"k8s-resource-adjustment/internal/report"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *report.Report {
+	return &report.Report{
+		RunID:    "run-7",
+		Env:      "prod",
+		Started:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Duration: 3 * time.Second,
+		Repositories: []report.Repository{
+			{
+				Repository: "platform/api",
+				Status:     report.StatusUpdated,
+				Commit:     "0123456789abcdef0123456789abcdef01234567",
+				Changes: []commitmsg.Change{{
+					Kind: "Deployment", Name: "api", Container: "app",
+					Values: []commitmsg.Value{
+						{Field: "requests.cpu", Old: "100m", New: "200m"},
+						{Field: "limits.memory", New: "256Mi"},
+					},
+				}},
+				Duration: 1500 * time.Millisecond,
+				Output:   "Updated file content and pushed to remote!!!\n",
+			},
+			{
+				Repository: "platform/web",
+				Status:     report.StatusFailed,
+				Category:   report.CategoryClone,
+				Error:      "authentication required",
+				Duration:   200 * time.Millisecond,
+			},
+			{
+				Repository: "platform/jobs",
+				Status:     report.StatusSkipped,
+				Category:   report.CategoryGuardrail,
+				Error:      "cpu request 8 | above 4",
+			},
+		},
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testReport().WriteJSON(&buf))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "run-7", got["runId"])
+	assert.Equal(t, 3.0, got["duration"])
+
+	repos := got["repositories"].([]any)
+	require.Len(t, repos, 3)
+	assert.Equal(t, map[string]any{
+		"repository": "platform/api",
+		"status":     "updated",
+		"commit":     "0123456789abcdef0123456789abcdef01234567",
+		"changes": []any{map[string]any{
+			"kind": "Deployment", "name": "api", "container": "app",
+			"values": []any{
+				map[string]any{"field": "requests.cpu", "old": "100m", "new": "200m"},
+				map[string]any{"field": "limits.memory", "new": "256Mi"},
+			},
+		}},
+		"duration": 1.5,
+	}, repos[0])
+	assert.Equal(t, map[string]any{
+		"repository": "platform/web",
+		"status":     "failed",
+		"category":   "clone",
+		"error":      "authentication required",
+		"duration":   0.2,
+	}, repos[1])
+}
+
+func TestReport_WriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testReport().WriteMarkdown(&buf))
+	assert.Equal(t, "## Resource adjustment run-7\n"+
+		"\n"+
+		"Environment `prod`: 3 repositories: 1 updated, 1 skipped, 1 failed.\n"+
+		"\n"+
+		"| Repository | Status | Changes | Commit | Duration | Error |\n"+
+		"|---|---|---|---|---|---|\n"+
+		"| platform/api | updated | Deployment/api app requests.cpu: 100m -> 200m<br>Deployment/api app limits.memory: unset -> 256Mi | `0123456789ab` | 1.5s |  |\n"+
+		"| platform/web | failed |  |  | 200ms | clone: authentication required |\n"+
+		"| platform/jobs | skipped |  |  | 0s | guardrail: cpu request 8 \\| above 4 |\n",
+		buf.String())
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	r := testReport()
+	r.Repositories = append(r.Repositories, report.Repository{
+		Repository: "platform/batch",
+		Status:     report.StatusCanceled,
+		Category:   report.CategoryTimeout,
+		Error:      "context canceled",
+	})
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteJUnit(&buf))
+
+	var got struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Skipped  int    `xml:"skipped,attr"`
+			Cases    []struct {
+				Name    string `xml:"name,attr"`
+				Time    string `xml:"time,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Type    string `xml:"type,attr"`
+				} `xml:"failure"`
+				Skipped   *struct{} `xml:"skipped"`
+				SystemOut string    `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &got))
+	require.Len(t, got.Suites, 1)
+	suite := got.Suites[0]
+	assert.Equal(t, "resource-adjustment.prod", suite.Name)
+	assert.Equal(t, []int{4, 2, 1}, []int{suite.Tests, suite.Failures, suite.Skipped})
+	require.Len(t, suite.Cases, 4)
+
+	assert.Equal(t, "platform/api", suite.Cases[0].Name)
+	assert.Equal(t, "1.500", suite.Cases[0].Time)
+	assert.Nil(t, suite.Cases[0].Failure)
+	assert.Nil(t, suite.Cases[0].Skipped)
+	assert.Equal(t, "Updated file content and pushed to remote!!!\n", suite.Cases[0].SystemOut)
+
+	require.NotNil(t, suite.Cases[1].Failure)
+	assert.Equal(t, "authentication required", suite.Cases[1].Failure.Message)
+	assert.Equal(t, "clone", suite.Cases[1].Failure.Type)
+
+	require.NotNil(t, suite.Cases[2].Failure)
+	assert.Equal(t, "cpu request 8 | above 4", suite.Cases[2].Failure.Message)
+	assert.Equal(t, "guardrail", suite.Cases[2].Failure.Type)
+	assert.Nil(t, suite.Cases[2].Skipped)
+
+	assert.Nil(t, suite.Cases[3].Failure)
+	assert.NotNil(t, suite.Cases[3].Skipped)
+}
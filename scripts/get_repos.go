@@ -1,46 +1,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strings"
+	"syscall"
+
+	"k8s-resource-adjustment/internal/gitops"
+	"k8s-resource-adjustment/internal/provider"
 
-	"github.com/jdxcode/netrc"
 	"github.com/joho/godotenv"
-	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
-func getGitLabToken(gitlabBaseURL string) (string, error) {
+// getToken returns the token from the environment variable tokenEnv or, if it
+// is not set, the password of the .netrc entry matching the host of baseURL.
+func getToken(tokenEnv, baseURL string) (string, error) {
 	// First, try to get the token from the environment variable
-	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+	if token := os.Getenv(tokenEnv); token != "" {
 		return token, nil
 	}
 
 	// If not found, try to get it from the .netrc file
-	netrcPath := filepath.Join(os.Getenv("HOME"), ".netrc")
-	n, err := netrc.Parse(netrcPath)
+	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("GITLAB_TOKEN not set and .netrc file not found at %s", netrcPath)
-		}
-		return "", fmt.Errorf("error parsing .netrc file: %w", err)
+		return "", fmt.Errorf("failed to parse base URL: %w", err)
 	}
+	// API hosts such as api.github.com share the credentials of github.com.
+	hostname := strings.TrimPrefix(parsedURL.Hostname(), "api.")
 
-	parsedURL, err := url.Parse(gitlabBaseURL)
+	_, password, err := gitops.NetrcCredentials("", hostname)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse GITLAB_BASE_URL: %w", err)
+		return "", fmt.Errorf("%s not set: %w", tokenEnv, err)
 	}
-	hostname := parsedURL.Hostname()
-
-	machine := n.Machine(hostname)
-	if machine == nil {
-		return "", fmt.Errorf("no entry for %s found in .netrc file", hostname)
-	}
-
-	return machine.Get("password"), nil
+	return password, nil
 }
 
 func main() {
@@ -50,53 +46,46 @@ func main() {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
 
-	// Get GitLab base URL, default to gitlab.com
-	gitlabBaseURL := os.Getenv("GITLAB_BASE_URL")
-	if gitlabBaseURL == "" {
-		gitlabBaseURL = "https://gitlab.com"
+	providerName := os.Getenv("PROVIDER")
+	if providerName == "" {
+		providerName = "gitlab"
+	}
+
+	// Resolve the base URL, token and group of the provider
+	var baseURL, tokenEnv, groupEnv string
+	switch providerName {
+	case "github":
+		baseURL, tokenEnv, groupEnv = os.Getenv("GITHUB_BASE_URL"), "GITHUB_TOKEN", "GITHUB_ORG"
+		if baseURL == "" {
+			baseURL = "https://api.github.com"
+		}
+	default:
+		baseURL, tokenEnv, groupEnv = os.Getenv("GITLAB_BASE_URL"), "GITLAB_TOKEN", "GITLAB_GROUP_ID"
+		if baseURL == "" {
+			baseURL = "https://gitlab.com"
+		}
 	}
 
-	// Get GitLab token
-	gitlabToken, err := getGitLabToken(gitlabBaseURL)
+	token, err := getToken(tokenEnv, baseURL)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	groupID := os.Getenv("GITLAB_GROUP_ID")
-	if groupID == "" {
-		log.Fatal("GITLAB_GROUP_ID environment variable not set")
+	group := os.Getenv(groupEnv)
+	if group == "" {
+		log.Fatalf("%s environment variable not set", groupEnv)
 	}
 
-	// Create a new GitLab client
-	git, err := gitlab.NewClient(gitlabToken, gitlab.WithBaseURL(gitlabBaseURL))
+	p, err := provider.New(providerName, baseURL, token)
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	// List all projects in the group, handling pagination
-	var allProjects []*gitlab.Project
-	opt := &gitlab.ListGroupProjectsOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: 100,
-		},
-	}
-
-	for {
-		projects, resp, err := git.Groups.ListGroupProjects(groupID, opt)
-		if err != nil {
-			log.Fatalf("Failed to list projects: %v", err)
-		}
-		allProjects = append(allProjects, projects...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
-	}
-
-	// Extract repository URLs
-	var repoURLs []string
-	for _, project := range allProjects {
-		repoURLs = append(repoURLs, project.PathWithNamespace)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	repoURLs, err := p.ListRepositories(ctx, group)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Write the REPO_URLS back to the .env file